@@ -0,0 +1,171 @@
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// insertBatch collects consecutive new rows of the same table and columns
+// so they can be inserted with a single statement
+type insertBatch struct {
+	indices []int // index of every row within the fixture
+	rows    []Row
+	pks     map[string]bool
+	copy    bool // insert the rows with COPY when possible
+	maxArgs int  // values bound by a single INSERT at most, 0 for no limit
+}
+
+// accepts reports whether row can be inserted along with the batched rows
+func (batch *insertBatch) accepts(row *Row) bool {
+	if len(batch.rows) == 0 {
+		return true
+	}
+	head := &batch.rows[0]
+	if head.Table != row.Table || len(head.insertColumns) != len(row.insertColumns) ||
+		head.OverrideSystemValue != row.OverrideSystemValue {
+		return false
+	}
+	for i, column := range head.insertColumns {
+		if row.insertColumns[i] != column {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether a row matching the same values as row is waiting
+// in the batch, its existence check would not see the batched row yet
+func (batch *insertBatch) contains(row *Row) bool {
+	return len(batch.rows) > 0 && batch.rows[0].Table == row.Table &&
+		batch.pks[fmt.Sprintf("%#v", row.matchValues)]
+}
+
+// add appends the i-th row of the fixture to the batch
+func (batch *insertBatch) add(i int, row Row) {
+	if len(batch.rows) == 0 {
+		batch.indices = nil
+		batch.pks = make(map[string]bool)
+	}
+	batch.indices = append(batch.indices, i)
+	batch.rows = append(batch.rows, row)
+	batch.pks[fmt.Sprintf("%#v", row.matchValues)] = true
+}
+
+// flush inserts the batched rows and empties the batch. The rows are split
+// over several INSERT statements when they bind more than maxArgs values
+func (batch *insertBatch) flush(ctx context.Context, tx queryer, driver string) error {
+	if len(batch.rows) == 0 {
+		return nil
+	}
+	rows, indices := batch.rows, batch.indices
+	batch.rows = nil
+
+	if sqlTx, ok := tx.(*sql.Tx); ok && batch.copy && canCopy(rows) {
+		if err := copyRows(ctx, sqlTx, rows); err != nil {
+			return newQueryError(indices[0], &rows[0], copyQuery(&rows[0]), nil, err)
+		}
+		return nil
+	}
+
+	start := 0
+	var values []interface{}
+	for k := range rows {
+		rowValues := rows[k].GetInsertValues()
+		if k > start && batch.maxArgs > 0 && len(values)+len(rowValues) > batch.maxArgs {
+			if err := insertRows(ctx, tx, indices[start], rows[start:k], values, driver); err != nil {
+				return err
+			}
+			start, values = k, nil
+		}
+		values = append(values, rowValues...)
+	}
+	return insertRows(ctx, tx, indices[start], rows[start:], values, driver)
+}
+
+// insertRows inserts rows with a single multi-values INSERT binding values,
+// the first of the rows is the i-th row of the fixture
+func insertRows(ctx context.Context, tx queryer, i int, rows []Row, values []interface{}, driver string) error {
+	query := batchInsertQuery(rows, driver)
+	if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+		return newQueryError(i, &rows[0], query, values, err)
+	}
+	return nil
+}
+
+// maxArgs returns how many values a single statement of driver can bind,
+// 0 when the limit is not known
+func maxArgs(driver string) int {
+	switch driver {
+	case postgresDriver, mysqlDriver:
+		return 65535
+	case sqliteDriver, sqlite3Driver:
+		return 999
+	case sqlserverDriver, mssqlDriver:
+		return 2100
+	}
+	return 0
+}
+
+// canCopy reports whether rows can be copied, COPY only takes values so
+// none of them may be an EXPR() expression
+func canCopy(rows []Row) bool {
+	for _, row := range rows {
+		for _, value := range row.insertValues {
+			if _, ok := value.(exprValue); ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// copyRows inserts rows sharing the same table and columns with a COPY
+// statement, lib/pq streams the values of every execution of the prepared
+// statement and completes the copy on the final execution without values
+func copyRows(ctx context.Context, tx *sql.Tx, rows []Row) error {
+	stmt, err := tx.PrepareContext(ctx, copyQuery(&rows[0]))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := range rows {
+		if _, err := stmt.ExecContext(ctx, rows[i].GetInsertValues()...); err != nil {
+			return err
+		}
+	}
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
+
+// copyQuery returns the Postgres COPY statement reading the insert columns
+// of row from the client
+func copyQuery(row *Row) string {
+	return fmt.Sprintf(
+		`COPY %s(%s) FROM STDIN`,
+		quoteTable(row.quoter(postgresDriver), row.Table),
+		strings.Join(row.GetInsertColumns(postgresDriver), ", "),
+	)
+}
+
+// batchInsertQuery returns a multi-values INSERT query for rows sharing the
+// same table and columns
+func batchInsertQuery(rows []Row, driver string) string {
+	head := &rows[0]
+	values := make([]string, len(rows))
+	bound := 0
+	for i := range rows {
+		placeholders := rows[i].GetInsertPlaceholdersFrom(driver, bound+1)
+		values[i] = fmt.Sprintf("(%s)", strings.Join(placeholders, ", "))
+		bound += len(rows[i].GetInsertValues())
+	}
+	return fmt.Sprintf(
+		`INSERT INTO %s(%s)%s VALUES%s`,
+		quoteTable(head.quoter(driver), head.Table),
+		strings.Join(head.GetInsertColumns(driver), ", "),
+		overridingClause(head, driver),
+		strings.Join(values, ", "),
+	)
+}
@@ -0,0 +1,146 @@
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertBatch(t *testing.T) {
+	rows := []Row{
+		{Table: "some_table", PK: map[string]interface{}{"id": 1}, Fields: map[string]interface{}{"string_field": "foo"}},
+		{Table: "some_table", PK: map[string]interface{}{"id": 2}, Fields: map[string]interface{}{"string_field": "bar"}},
+		{Table: "some_table", PK: map[string]interface{}{"id": 3}, Fields: map[string]interface{}{"boolean_field": true}},
+		{Table: "other_table", PK: map[string]interface{}{"id": 4}, Fields: map[string]interface{}{"string_field": "baz"}},
+	}
+	for i := range rows {
+		rows[i].Init()
+	}
+
+	batch := new(insertBatch)
+	batch.add(0, rows[0])
+
+	// Only rows of the same table and columns can join the batch
+	assert.True(t, batch.accepts(&rows[1]))
+	assert.False(t, batch.accepts(&rows[2]))
+	assert.False(t, batch.accepts(&rows[3]))
+
+	// A batched primary key is recognised
+	assert.True(t, batch.contains(&rows[0]))
+	assert.False(t, batch.contains(&rows[1]))
+
+	// Placeholders are numbered across all the rows
+	assert.Equal(
+		t,
+		`INSERT INTO "some_table"("id", "string_field") VALUES($1, $2), ($3, $4)`,
+		batchInsertQuery(rows[:2], "postgres"),
+	)
+	assert.Equal(
+		t,
+		"INSERT INTO `some_table`(`id`, `string_field`) VALUES(?, ?), (?, ?)",
+		batchInsertQuery(rows[:2], "mysql"),
+	)
+
+	// Expressions are not bound, the following placeholders are renumbered
+	exprRows := []Row{
+		{Table: "some_table", PK: map[string]interface{}{"id": 1}, Fields: map[string]interface{}{"string_field": "EXPR(upper('foo'))"}},
+		{Table: "some_table", PK: map[string]interface{}{"id": 2}, Fields: map[string]interface{}{"string_field": "bar"}},
+	}
+	for i := range exprRows {
+		exprRows[i].Init()
+	}
+	assert.Equal(
+		t,
+		`INSERT INTO "some_table"("id", "string_field") VALUES($1, upper('foo')), ($2, $3)`,
+		batchInsertQuery(exprRows, "postgres"),
+	)
+}
+
+func TestCopyQuery(t *testing.T) {
+	rows := []Row{
+		{Table: "billing.invoices", PK: map[string]interface{}{"id": 1}, Fields: map[string]interface{}{"amount": 100}},
+		{Table: "billing.invoices", PK: map[string]interface{}{"id": 2}, Fields: map[string]interface{}{"amount": "EXPR(10 * 10)"}},
+	}
+	for i := range rows {
+		rows[i].Init()
+	}
+
+	assert.Equal(t, `COPY "billing"."invoices"("id", "amount") FROM STDIN`, copyQuery(&rows[0]))
+
+	// Expressions cannot be copied
+	assert.True(t, canCopy(rows[:1]))
+	assert.False(t, canCopy(rows))
+}
+
+// recordingQueryer records the statements executed through it
+type recordingQueryer struct {
+	queries []string
+	args    [][]interface{}
+}
+
+func (q *recordingQueryer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	q.queries = append(q.queries, query)
+	q.args = append(q.args, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (q *recordingQueryer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func TestInsertBatchSplitsOverMaxArgs(t *testing.T) {
+	batch := &insertBatch{maxArgs: 5}
+	for i := 0; i < 5; i++ {
+		row := Row{Table: "some_table", PK: map[string]interface{}{"id": i + 1}, Fields: map[string]interface{}{"string_field": "foo"}}
+		row.Init()
+		batch.add(i, row)
+	}
+
+	// Two rows of two values fit under five values
+	q := new(recordingQueryer)
+	assert.Nil(t, batch.flush(context.Background(), q, "postgres"))
+	assert.Equal(t, []string{
+		`INSERT INTO "some_table"("id", "string_field") VALUES($1, $2), ($3, $4)`,
+		`INSERT INTO "some_table"("id", "string_field") VALUES($1, $2), ($3, $4)`,
+		`INSERT INTO "some_table"("id", "string_field") VALUES($1, $2)`,
+	}, q.queries)
+	assert.Equal(t, [][]interface{}{
+		{1, "foo", 2, "foo"},
+		{3, "foo", 4, "foo"},
+		{5, "foo"},
+	}, q.args)
+
+	// A row binding more values than allowed is inserted on its own
+	batch = &insertBatch{maxArgs: 1}
+	for i := 0; i < 2; i++ {
+		row := Row{Table: "some_table", PK: map[string]interface{}{"id": i + 1}, Fields: map[string]interface{}{"string_field": "foo"}}
+		row.Init()
+		batch.add(i, row)
+	}
+	q = new(recordingQueryer)
+	assert.Nil(t, batch.flush(context.Background(), q, "sqlite"))
+	assert.Len(t, q.queries, 2)
+}
+
+func TestExecRowReturningIntoOnOracle(t *testing.T) {
+	row := &Row{
+		Table:     "users",
+		PK:        map[string]interface{}{"id": 1},
+		Returning: []string{"created_at"},
+	}
+	row.Init()
+
+	// The returned columns are bound as output parameters after the values
+	q := new(recordingQueryer)
+	query, values := BuildInsert(row, "oracle")
+	assert.Nil(t, execRow(context.Background(), q, row, "oracle", query, values))
+	assert.Equal(t, []string{`INSERT INTO "USERS"("ID") VALUES(:1) RETURNING "CREATED_AT" INTO :2`}, q.queries)
+	if assert.Len(t, q.args, 1) && assert.Len(t, q.args[0], 2) {
+		assert.Equal(t, 1, q.args[0][0])
+		assert.IsType(t, sql.Out{}, q.args[0][1])
+	}
+	assert.Equal(t, map[string]interface{}{"created_at": nil}, row.Returned())
+}
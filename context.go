@@ -0,0 +1,264 @@
+package fixtures
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// Context holds the options used when loading fixtures, the zero value
+// loads fixtures the same way Load does
+type Context struct {
+	// Truncate empties every table referenced by the fixtures before any
+	// of their rows are loaded
+	Truncate bool
+
+	// Upsert writes each row with a single INSERT ... ON CONFLICT statement
+	// on Postgres or INSERT ... ON DUPLICATE KEY UPDATE on MySQL instead of
+	// a SELECT followed by an INSERT or UPDATE. Other drivers ignore it
+	Upsert bool
+
+	// Batch inserts consecutive new rows of the same table and columns
+	// with a single multi-values INSERT statement, Oracle ignores it
+	Batch bool
+
+	// MaxArgs caps the values bound by a single batched INSERT, larger
+	// batches are split over several statements. When zero the limit of the
+	// driver is used, 65535 on Postgres and MySQL, 999 on SQLite and 2100 on
+	// SQL Server
+	MaxArgs int
+
+	// Copy inserts the rows batched with Batch using COPY ... FROM STDIN on
+	// Postgres, which is much faster for large loads. It relies on the copy
+	// support of the lib/pq driver. Batches with EXPR() values are still
+	// inserted with INSERT and other drivers ignore it
+	Copy bool
+
+	// ContinueOnError wraps every row in a savepoint, a failing row is
+	// rolled back to its savepoint and skipped while the other rows are
+	// still committed. The errors of the skipped rows are returned joined
+	// with errors.Join. Savepoints are supported by Postgres, MySQL (InnoDB),
+	// SQLite and SQL Server. Batch is ignored when it is set
+	ContinueOnError bool
+
+	// DependsOn maps a table to the tables it depends on, such as the
+	// tables its foreign keys reference. The rows of a fixture are loaded
+	// after the rows of the tables their table depends on, rows of the same
+	// table keep their order. Deleted rows are deleted first, before the
+	// rows of the tables their table depends on. Cyclic dependencies are
+	// reported as an error
+	DependsOn map[string][]string
+
+	// DeferConstraints defers foreign key checks until every row has been
+	// loaded, so rows referencing each other can be loaded in any order.
+	// Postgres defers constraints declared DEFERRABLE with SET CONSTRAINTS,
+	// as does Oracle, MySQL disables FOREIGN_KEY_CHECKS for the load and
+	// SQLite sets the defer_foreign_keys pragma. Other drivers ignore it
+	DeferConstraints bool
+
+	// ReuseArgs makes UPDATE queries compare the primary key, or match_on
+	// columns, with the placeholders of the values they are set to instead
+	// of binding the same values twice. Only drivers with numbered
+	// placeholders, Postgres and SQL Server, can reuse them
+	ReuseArgs bool
+
+	// AssumeEmpty skips checking whether each row exists and always inserts
+	// it, loading a row which already exists fails with the driver's
+	// duplicate key error. Meant for freshly created or truncated tables
+	AssumeEmpty bool
+
+	// TableMode sets how the rows of some tables are written, overriding
+	// Upsert and AssumeEmpty for these tables
+	TableMode map[string]Mode
+
+	// SkipUnchanged leaves existing rows which already hold the values of
+	// the fixture alone rather than updating them, the existence check also
+	// compares the columns. ON_UPDATE_NOW() columns are not compared, as
+	// they would always differ. Rows written with upserts or always
+	// inserted ignore it
+	SkipUnchanged bool
+
+	// Rollback rolls the transaction of a load back once every row is
+	// loaded instead of committing it, the rows do not persist. It checks
+	// fixtures load against a real database, rows to inspect before rolling
+	// them back are loaded with LoadTx in a transaction of the caller
+	Rollback bool
+
+	// Retry runs the transaction of a load again when it fails with a
+	// transient error, such as a Postgres serialization failure. Loads
+	// within a transaction of the caller or without one are never retried
+	Retry RetryPolicy
+
+	// BeforeRow, when set, is called with every row before it is written
+	BeforeRow func(row *Row)
+
+	// AfterRow, when set, is called with every row once it is written along
+	// with the error writing it, if any. A batched row is only inserted with
+	// its batch, later on
+	AfterRow func(row *Row, err error)
+
+	// Progress, when set, is called after every row with the number of rows
+	// handled so far and the number of rows of all the fixtures loaded
+	// together, rows skipped by their when guard included
+	Progress func(done, total int)
+
+	// Vars holds the values of VAR(name) fields and primary keys, of the
+	// {name} variables of table names, such as users_{tenant}, and of the
+	// variables rows are guarded by with when, a fixture using a variable
+	// missing from Vars fails to load before any query
+	Vars map[string]interface{}
+
+	// ColumnMap maps the column names written in fixtures to the columns of
+	// the database, such as createdAt to created_at, for pk, fields and the
+	// columns listed by rows. Names missing from it are used as is. Types
+	// and Returned keep the names of the fixture
+	ColumnMap map[string]string
+
+	// ParseTimestamps binds string values formatted as RFC3339 timestamps,
+	// such as 2023-01-02T15:04:05Z, as time.Time values. Columns given a
+	// type by the row keep their type
+	ParseTimestamps bool
+
+	// SerializeComplex binds the lists and maps of fields, which drivers
+	// cannot bind as is, as strings. Lists become array literals such as
+	// {1,2,3} on Postgres and JSON on other drivers, maps become JSON, for
+	// array, json and jsonb columns
+	SerializeComplex bool
+
+	// ValueTransformer, when set, is called with the value of every column
+	// bound by a row and the value it returns is bound instead, such as for
+	// hashing passwords or encrypting columns. The values of markers, such
+	// as NULL() or ON_INSERT_UUID(), are not transformed. An error fails the
+	// row
+	ValueTransformer func(table, column string, value interface{}) (interface{}, error)
+
+	// NewUUID generates the values of ON_INSERT_UUID() columns, random
+	// UUIDs are used when it is nil
+	NewUUID func() string
+
+	// Now returns the time used for ON_INSERT_NOW() and ON_UPDATE_NOW()
+	// columns, time.Now is used when it is nil
+	Now func() time.Time
+
+	// Quote escapes every table and column name of the generated queries,
+	// the segments of a schema qualified table are escaped one by one. By
+	// default MySQL identifiers are quoted with backticks and those of other
+	// drivers with double quotes
+	Quote func(identifier string) string
+
+	// UnquotedIdentifiers writes table and column names as they are spelled
+	// in the fixtures, for schemas created without quotes whose names
+	// Postgres folded to lowercase. Names which are reserved words, such as
+	// order or user, are then rejected by the database. Quote takes
+	// precedence when both are set
+	UnquotedIdentifiers bool
+
+	// Dialect writes the placeholders and quotes the identifiers of queries
+	// instead of the dialect of the driver, such as for a driver speaking
+	// another SQL dialect. Quote and UnquotedIdentifiers take precedence over
+	// its quoting. Which statements are used, such as upserts, still depends
+	// on the driver. A NamedDialect binds values by name, rows are then
+	// never batched. Drivers other than the known ones are only accepted
+	// with a Dialect
+	Dialect Dialect
+
+	// SkipSequenceFix leaves the Postgres sequences of primary keys alone
+	// after loading, for tables whose keys are never generated or whose
+	// sequences should not be touched, such as session private temporary
+	// tables. Sequences is ignored when it is set
+	SkipSequenceFix bool
+
+	// Sequences maps a Postgres primary key column, written as table.column
+	// such as billing.invoices.id, to the sequence fixed after loading it
+	// when the sequence cannot be found with pg_get_serial_sequence, as for
+	// sequences not owned by their column
+	Sequences map[string]string
+
+	// Logger receives warnings such as empty rows being skipped or rows
+	// left untouched as they have nothing to write, nothing is logged when
+	// it is nil
+	Logger *log.Logger
+
+	// DryRun appends the statements a load would run to Statements instead
+	// of executing them, the database is not used at all. Rows are never
+	// batched and both the INSERT and UPDATE of a row are recorded
+	DryRun bool
+
+	// Statements holds the statements collected in DryRun mode
+	Statements []Statement
+}
+
+// Mode is how the rows of a table are written, see Context.TableMode
+type Mode int
+
+const (
+	// ModeDefault inserts missing rows and updates existing ones, unless
+	// Upsert or AssumeEmpty are set
+	ModeDefault Mode = iota
+
+	// ModeUpsert writes every row with a single statement as Upsert does,
+	// drivers without upserts use ModeDefault instead
+	ModeUpsert
+
+	// ModeInsertOnly always inserts rows as AssumeEmpty does
+	ModeInsertOnly
+
+	// ModeUpdateOnly updates the rows which exist, missing rows are left
+	// out
+	ModeUpdateOnly
+)
+
+// RetryPolicy tells how loads failing with transient errors are retried, the
+// zero value never retries
+type RetryPolicy struct {
+	// MaxAttempts is how many times a load is attempted at most, including
+	// the first attempt
+	MaxAttempts int
+
+	// Backoff is the wait before the second attempt, it doubles before
+	// every further attempt
+	Backoff time.Duration
+
+	// Retryable reports whether an error is transient, when nil Postgres
+	// serialization failures and deadlocks, SQLSTATE 40001 and 40P01, are
+	Retryable func(err error) bool
+}
+
+// retryable reports whether a load failing with err may be attempted again
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	switch sqlState(err) {
+	case "40001", "40P01":
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait after the given failed attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	return p.Backoff << uint(attempt-1)
+}
+
+// sqlState returns the SQLSTATE code of a database error, if any. Errors of
+// lib/pq expose it with Get('C') and those of other drivers, such as pgx,
+// with SQLState()
+func sqlState(err error) string {
+	var stater interface{ SQLState() string }
+	if errors.As(err, &stater) {
+		return stater.SQLState()
+	}
+	var getter interface{ Get(k byte) string }
+	if errors.As(err, &getter) {
+		return getter.Get('C')
+	}
+	return ""
+}
+
+// logf logs a message with c.Logger, if any
+func (c *Context) logf(format string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Printf(format, args...)
+	}
+}
@@ -0,0 +1,147 @@
+package fixtures
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect writes the parts of queries which differ between SQL dialects,
+// see Context.Dialect
+type Dialect interface {
+	// Placeholder returns the n-th (1-based) placeholder of a query
+	Placeholder(n int) string
+
+	// Quote escapes a table or column name
+	Quote(identifier string) string
+}
+
+// NamedDialect is a Dialect binding values by name rather than by position,
+// such as :name on Oracle or @name on SQL Server. The values of its queries
+// are sql.NamedArg values named after their column, a column set and compared
+// by the same query is bound once
+type NamedDialect interface {
+	Dialect
+
+	// NamedPlaceholder returns the placeholder of the value called name
+	NamedPlaceholder(name string) string
+}
+
+// postgresDialect numbers placeholders $1, $2 and so on and quotes
+// identifiers with ANSI double quotes
+type postgresDialect struct{}
+
+func (postgresDialect) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (postgresDialect) Quote(identifier string) string {
+	return fmt.Sprintf("\"%s\"", identifier)
+}
+
+// mysqlDialect uses ? placeholders and quotes identifiers with backticks
+type mysqlDialect struct{}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysqlDialect) Quote(identifier string) string {
+	return fmt.Sprintf("`%s`", identifier)
+}
+
+// sqliteDialect uses ? placeholders and quotes identifiers with ANSI double
+// quotes, which SQLite always treats as an identifier in column lists
+type sqliteDialect struct{}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (sqliteDialect) Quote(identifier string) string {
+	return fmt.Sprintf("\"%s\"", identifier)
+}
+
+// sqlserverDialect numbers placeholders @p1, @p2 and so on and quotes
+// identifiers with square brackets
+type sqlserverDialect struct{}
+
+func (sqlserverDialect) Placeholder(n int) string {
+	return fmt.Sprintf("@p%d", n)
+}
+
+func (sqlserverDialect) Quote(identifier string) string {
+	return fmt.Sprintf("[%s]", identifier)
+}
+
+// oracleDialect numbers placeholders :1, :2 and so on and quotes identifiers
+// with ANSI double quotes in uppercase, as Oracle folds unquoted names to
+// uppercase
+type oracleDialect struct{}
+
+func (oracleDialect) Placeholder(n int) string {
+	return fmt.Sprintf(":%d", n)
+}
+
+func (oracleDialect) Quote(identifier string) string {
+	return fmt.Sprintf("\"%s\"", strings.ToUpper(identifier))
+}
+
+// dialectFor returns the dialect of driver, unknown drivers get the SQLite
+// one with ? placeholders and double quotes
+func dialectFor(driver string) Dialect {
+	switch driver {
+	case postgresDriver:
+		return postgresDialect{}
+	case mysqlDriver:
+		return mysqlDialect{}
+	case sqlserverDriver, mssqlDriver:
+		return sqlserverDialect{}
+	case oracleDriver, godrorDriver:
+		return oracleDialect{}
+	}
+	return sqliteDialect{}
+}
+
+// placeholder returns the n-th (1-based) placeholder of a query for driver
+func placeholder(driver string, n int) string {
+	return dialectFor(driver).Placeholder(n)
+}
+
+// knownDrivers lists the drivers whose dialect is known
+var knownDrivers = []string{
+	postgresDriver,
+	mysqlDriver,
+	sqliteDriver,
+	sqlite3Driver,
+	sqlserverDriver,
+	mssqlDriver,
+	oracleDriver,
+	godrorDriver,
+}
+
+// checkDriver rejects a driver whose dialect is not known, such as a
+// misspelled postgresql, unless c sets a Dialect for it
+func (c *Context) checkDriver(driver string) error {
+	if c.Dialect != nil {
+		return nil
+	}
+	for _, known := range knownDrivers {
+		if driver == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown driver %s, supported drivers are %s", driver, strings.Join(knownDrivers, ", "))
+}
+
+// checkReturning rejects the returning columns of row when the database of
+// driver has no RETURNING clause, such as MySQL and SQL Server
+func (c *Context) checkReturning(row *Row, driver string) error {
+	if len(row.Returning) == 0 || c.Dialect != nil {
+		return nil
+	}
+	switch driver {
+	case mysqlDriver, sqlserverDriver, mssqlDriver:
+		return fmt.Errorf("returning columns of table %s are not supported by driver %s", row.Table, driver)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// colonDialect numbers placeholders :1, :2 and so on and quotes identifiers
+// in uppercase, as some Oracle drivers expect
+type colonDialect struct{}
+
+func (colonDialect) Placeholder(n int) string {
+	return fmt.Sprintf(":%d", n)
+}
+
+func (colonDialect) Quote(identifier string) string {
+	return fmt.Sprintf("\"%s\"", strings.ToUpper(identifier))
+}
+
+func TestDialectFor(t *testing.T) {
+	for _, c := range []struct {
+		driver      string
+		placeholder string
+		quoted      string
+	}{
+		{"postgres", "$2", `"id"`},
+		{"mysql", "?", "`id`"},
+		{"sqlite", "?", `"id"`},
+		{"sqlite3", "?", `"id"`},
+		{"sqlserver", "@p2", "[id]"},
+		{"mssql", "@p2", "[id]"},
+		{"oracle", ":2", `"ID"`},
+		{"godror", ":2", `"ID"`},
+		{"unknown", "?", `"id"`},
+	} {
+		assert.Equal(t, c.placeholder, dialectFor(c.driver).Placeholder(2), c.driver)
+		assert.Equal(t, c.quoted, dialectFor(c.driver).Quote("id"), c.driver)
+	}
+}
+
+func TestDryRunWithDialect(t *testing.T) {
+	c := &Context{DryRun: true, Truncate: true, ReuseArgs: true, Dialect: colonDialect{}}
+	err := LoadWithContext(context.Background(), []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foo'
+`), nil, "odbc", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		{Query: `DELETE FROM "SOME_TABLE"`},
+		{Query: `SELECT EXISTS(SELECT 1 FROM "SOME_TABLE" WHERE "ID" = :1)`, Args: []interface{}{1}},
+		{Query: `INSERT INTO "SOME_TABLE"("ID", "STRING_FIELD") VALUES(:1, :2)`, Args: []interface{}{1, "foo"}},
+		{Query: `UPDATE "SOME_TABLE" SET "ID" = :1, "STRING_FIELD" = :2 WHERE "ID" = :1`, Args: []interface{}{1, "foo"}},
+	}, c.Statements)
+
+	// Quote takes precedence over the quoting of the dialect
+	c = &Context{DryRun: true, Dialect: colonDialect{}, Quote: func(identifier string) string { return identifier }}
+	err = LoadWithContext(context.Background(), []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+`), nil, "odbc", c)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT EXISTS(SELECT 1 FROM some_table WHERE id = :1)`, c.Statements[0].Query)
+}
+
+// colonNamedDialect binds values by name as :name
+type colonNamedDialect struct {
+	colonDialect
+}
+
+func (colonNamedDialect) NamedPlaceholder(name string) string {
+	return ":" + name
+}
+
+func TestDryRunWithNamedDialect(t *testing.T) {
+	c := &Context{DryRun: true, SkipUnchanged: true, Dialect: colonNamedDialect{}}
+	err := LoadWithContext(context.Background(), []byte(`
+---
+- table: 'users'
+  pk:
+    id: 1
+  fields:
+    email: 'foo@example.com'
+    updated_at: 'ON_UPDATE_NOW()'
+  match_on: ['email']
+`), nil, "odbc", c)
+	assert.Nil(t, err)
+	if !assert.Len(t, c.Statements, 3) {
+		return
+	}
+
+	// Values are named after their columns and bound once
+	assert.Equal(
+		t,
+		`SELECT EXISTS(SELECT 1 FROM "USERS" WHERE "EMAIL" = :email), `+
+			`EXISTS(SELECT 1 FROM "USERS" WHERE "EMAIL" = :email AND "ID" IS NOT DISTINCT FROM :id AND "EMAIL" IS NOT DISTINCT FROM :email)`,
+		c.Statements[0].Query,
+	)
+	assert.Equal(t, []interface{}{sql.Named("email", "foo@example.com"), sql.Named("id", 1)}, c.Statements[0].Args)
+	assert.Equal(t, `INSERT INTO "USERS"("ID", "EMAIL") VALUES(:id, :email)`, c.Statements[1].Query)
+	assert.Equal(t, []interface{}{sql.Named("id", 1), sql.Named("email", "foo@example.com")}, c.Statements[1].Args)
+	assert.Equal(
+		t,
+		`UPDATE "USERS" SET "ID" = :id, "EMAIL" = :email, "UPDATED_AT" = :updated_at WHERE "EMAIL" = :email`,
+		c.Statements[2].Query,
+	)
+	assert.Len(t, c.Statements[2].Args, 3)
+	assert.Equal(t, sql.Named("email", "foo@example.com"), c.Statements[2].Args[1])
+}
+
+func TestDryRunOracle(t *testing.T) {
+	c := &Context{DryRun: true, SkipUnchanged: true}
+	err := LoadWithContext(context.Background(), []byte(`
+---
+- table: 'users'
+  pk:
+    id: 1
+  fields:
+    email: 'foo@example.com'
+  returning: ['created_at']
+`), nil, "godror", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		{
+			Query: `SELECT CASE WHEN EXISTS(SELECT 1 FROM "USERS" WHERE "ID" = :1) THEN 1 ELSE 0 END, ` +
+				`CASE WHEN EXISTS(SELECT 1 FROM "USERS" WHERE "ID" = :2 AND DECODE("ID", :3, 1, 0) = 1 AND DECODE("EMAIL", :4, 1, 0) = 1) THEN 1 ELSE 0 END FROM DUAL`,
+			Args: []interface{}{1, 1, 1, "foo@example.com"},
+		},
+		{
+			Query: `INSERT INTO "USERS"("ID", "EMAIL") VALUES(:1, :2) RETURNING "CREATED_AT" INTO :3`,
+			Args:  []interface{}{1, "foo@example.com"},
+		},
+		{
+			Query: `UPDATE "USERS" SET "ID" = :1, "EMAIL" = :2 WHERE "ID" = :3 RETURNING "CREATED_AT" INTO :4`,
+			Args:  []interface{}{1, "foo@example.com", 1},
+		},
+	}, c.Statements)
+}
+
+func TestLoadRejectsUnknownDriver(t *testing.T) {
+	data := []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+`)
+
+	// Rejected before the database is used
+	err := Load(data, nil, "postgresql")
+	assert.EqualError(t, err, "Unknown driver postgresql, supported drivers are postgres, mysql, sqlite, sqlite3, sqlserver, mssql, oracle, godror")
+	err = Clean(data, nil, "postgresql")
+	assert.NotNil(t, err)
+
+	c := &Context{DryRun: true}
+	err = LoadWithContext(context.Background(), data, nil, "postgresql", c)
+	assert.NotNil(t, err)
+	assert.Empty(t, c.Statements)
+
+	// A dialect makes any driver usable
+	c = &Context{DryRun: true, Dialect: colonDialect{}}
+	err = LoadWithContext(context.Background(), data, nil, "postgresql", c)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, c.Statements)
+}
+
+func TestLoadRejectsUnsupportedReturning(t *testing.T) {
+	data := []byte(`
+---
+- table: 'users'
+  pk:
+    id: 1
+  returning: ['created_at']
+`)
+
+	// MySQL and SQL Server have no RETURNING clause
+	for _, driver := range []string{"mysql", "sqlserver", "mssql"} {
+		c := &Context{DryRun: true}
+		err := LoadWithContext(context.Background(), data, nil, driver, c)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "returning columns of table users are not supported by driver "+driver)
+		assert.Empty(t, c.Statements)
+	}
+
+	// The other drivers read them back
+	for _, driver := range []string{"postgres", "sqlite", "oracle"} {
+		c := &Context{DryRun: true}
+		err := LoadWithContext(context.Background(), data, nil, driver, c)
+		assert.Nil(t, err)
+	}
+}
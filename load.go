@@ -1,106 +1,1037 @@
 package fixtures
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
-	"strings"
+	"path/filepath"
+	"sort"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
+// ProcessingError is returned when a row of a fixture fails to load
+type ProcessingError struct {
+	Row    int           // 1-based index of the row within its fixture
+	Table  string        // table of the row, empty when unknown
+	Column string        // column which failed, empty when unknown
+	Query  string        // query which failed, empty when none did
+	Args   []interface{} // values bound to the failed query
+	Err    error         // underlying (usually driver) error
+}
+
+// Error implements the error interface
+func (e *ProcessingError) Error() string {
+	msg := fmt.Sprintf("Error loading row %d", e.Row)
+	if e.Table != "" {
+		msg += fmt.Sprintf(" of table %s", e.Table)
+	}
+	if e.Column != "" {
+		msg += fmt.Sprintf(" (column %s)", e.Column)
+	}
+	return fmt.Sprintf("%s: %s", msg, e.Err.Error())
+}
+
+// Unwrap returns the underlying error
+func (e *ProcessingError) Unwrap() error {
+	return e.Err
+}
+
 // NewProcessingError ...
 func NewProcessingError(row int, cause error) error {
-	return fmt.Errorf("Error loading row %d: %s", row, cause.Error())
+	return &ProcessingError{Row: row, Err: cause}
+}
+
+// newRowError returns a ProcessingError for the i-th (0-based) row of a
+// fixture, column is left empty when the failing column is not known
+func newRowError(i int, row *Row, column string, cause error) error {
+	return &ProcessingError{Row: i + 1, Table: row.Table, Column: column, Err: cause}
+}
+
+// newQueryError returns a ProcessingError for the i-th (0-based) row of a
+// fixture whose query failed with its values
+func newQueryError(i int, row *Row, query string, args []interface{}, cause error) error {
+	return &ProcessingError{Row: i + 1, Table: row.Table, Query: query, Args: args, Err: cause}
+}
+
+// Result counts the rows of a load by what was done with them
+type Result struct {
+	Inserted  int // rows inserted
+	Updated   int // existing rows updated
+	Deleted   int // rows marked for deletion, whether they existed or not
+	Upserted  int // rows written with Upsert, inserted and updated alike
+	Skipped   int // failing rows skipped with ContinueOnError
+	Unchanged int // existing rows left alone with SkipUnchanged
 }
 
 // NewFileError ...
 func NewFileError(filename string, cause error) error {
-	return fmt.Errorf("Error loading file %s: %s", filename, cause.Error())
+	return fmt.Errorf("Error loading file %s: %w", filename, cause)
 }
 
 // Load processes a YAML fixture and inserts/updates the database accordingly
 func Load(data []byte, db *sql.DB, driver string) error {
-	// Unmarshal the YAML data into a []Row slice
-	var rows []Row
-	if err := yaml.Unmarshal(data, &rows); err != nil {
-		return err
+	return LoadContext(context.Background(), data, db, driver)
+}
+
+// LoadContext is like Load but runs every query with ctx, so the load is
+// aborted and rolled back once ctx is cancelled or its deadline passes
+func LoadContext(ctx context.Context, data []byte, db *sql.DB, driver string) error {
+	return LoadWithContext(ctx, data, db, driver, new(Context))
+}
+
+// LoadWithContext is like LoadContext but uses the options held by c
+func LoadWithContext(ctx context.Context, data []byte, db *sql.DB, driver string, c *Context) error {
+	_, err := LoadWithResult(ctx, data, db, driver, c)
+	return err
+}
+
+// LoadWithResult is like LoadWithContext but also returns how many rows were
+// inserted, updated, deleted, upserted or skipped. Nothing is counted when
+// the load fails and is rolled back
+func LoadWithResult(ctx context.Context, data []byte, db *sql.DB, driver string, c *Context) (Result, error) {
+	parsed, err := parseFixtures([][]byte{data})
+	if err != nil {
+		return Result{}, err
 	}
+	return c.load(ctx, parsed, db, driver)
+}
 
-	// Begin a transaction
-	tx, err := db.Begin()
+// Target is a database LoadTargets loads fixtures into, along with the name
+// of its driver
+type Target struct {
+	DB     *sql.DB
+	Driver string
+}
+
+// LoadTargets loads a fixture into several databases, each one within its own
+// transaction. The transactions are only committed once the fixture loaded
+// into every target, a failing target rolls all of them back. A commit
+// failing after others succeeded cannot be undone. Loads are not retried
+func LoadTargets(ctx context.Context, data []byte, targets []Target, c *Context) error {
+	parsed, err := parseFixtures([][]byte{data})
 	if err != nil {
 		return err
 	}
+	for _, target := range targets {
+		if err := c.checkDriver(target.Driver); err != nil {
+			return err
+		}
+		if err := c.validateFixtures(parsed, target.Driver); err != nil {
+			return err
+		}
+	}
 
-	// Iterate over rows define in the fixture
-	for i, row := range rows {
-		// Load internat struct variables
-		row.Init()
-
-		// Run a SELECT query to find out if we need to insert or UPDATE
-		selectQuery := fmt.Sprintf(
-			`SELECT COUNT(*) FROM "%s" WHERE %s`,
-			row.Table,
-			row.GetWhere(driver, 0),
-		)
-		var count int
-		err = tx.QueryRow(selectQuery, row.GetPKValues()...).Scan(&count)
-		if err != nil {
+	// Only collect the statements of every target
+	if c.DryRun {
+		for _, target := range targets {
+			if err := c.render(parsed, target.Driver); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	txs := make([]*sql.Tx, 0, len(targets))
+	rollback := func(txs []*sql.Tx) {
+		for _, tx := range txs {
 			tx.Rollback() // rollback the transaction
-			return NewProcessingError(i+1, err)
-		}
-
-		if count == 0 {
-			// Primary key not found, let's run an INSERT query
-			insertQuery := fmt.Sprintf(
-				`INSERT INTO "%s"(%s) VALUES(%s)`,
-				row.Table,
-				strings.Join(row.GetInsertColumns(), ", "),
-				strings.Join(row.GetInsertPlaceholders(driver), ", "),
-			)
-			_, err := tx.Exec(insertQuery, row.GetInsertValues()...)
+		}
+	}
+	var failed []error
+	for i, target := range targets {
+		tx, err := target.DB.BeginTx(ctx, nil)
+		if err != nil {
+			rollback(txs)
+			return fmt.Errorf("Error loading target %d (%s): %w", i+1, target.Driver, err)
+		}
+		txs = append(txs, tx)
+		targetFailed, err := c.loadTx(ctx, tx, parsed, target.Driver, new(Result))
+		if err != nil {
+			rollback(txs)
+			return fmt.Errorf("Error loading target %d (%s): %w", i+1, target.Driver, err)
+		}
+		failed = append(failed, targetFailed...)
+	}
+
+	if c.Rollback {
+		rollback(txs)
+		return errors.Join(failed...)
+	}
+	for i, tx := range txs {
+		if err := tx.Commit(); err != nil {
+			rollback(txs[i+1:])
+			return fmt.Errorf("Error loading target %d (%s): %w", i+1, targets[i].Driver, err)
+		}
+	}
+
+	// Report the rows skipped with ContinueOnError
+	return errors.Join(failed...)
+}
+
+// ParseRows unmarshals the rows of every document of a YAML fixture without
+// loading them, they can be inspected or changed and loaded with LoadRows
+func ParseRows(data []byte) ([]Row, error) {
+	return parseFixture(data)
+}
+
+// LoadRows is like LoadWithContext but loads rows built in Go rather than
+// parsed from a fixture, the rows are initialised internally. The values of
+// their Returning columns are available from Returned once loaded
+func LoadRows(ctx context.Context, rows []Row, db *sql.DB, driver string, c *Context) error {
+	_, err := c.load(ctx, [][]Row{rows}, db, driver)
+	return err
+}
+
+// LoadRow is like LoadRows but loads a single row
+func LoadRow(ctx context.Context, row Row, db *sql.DB, driver string, c *Context) error {
+	return LoadRows(ctx, []Row{row}, db, driver, c)
+}
+
+// Validate parses a YAML fixture and checks every row could be loaded,
+// without connecting to a database. The first invalid row is reported,
+// variables are not checked as their values are only known when loading
+func Validate(data []byte) error {
+	rows, err := parseFixture(data)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		if rows[i].empty() || rows[i].comment() {
+			continue
+		}
+		if err := rows[i].Validate(); err != nil {
+			return newRowError(i, &rows[i], "", err)
+		}
+	}
+	return nil
+}
+
+// ValidateAll is like Validate but reports every invalid row rather than the
+// first one, joined with errors.Join. The error of each row is available from
+// the Unwrap() []error method of the returned error
+func ValidateAll(data []byte) error {
+	rows, err := parseFixture(data)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range rows {
+		if rows[i].empty() || rows[i].comment() {
+			continue
+		}
+		if err := rows[i].Validate(); err != nil {
+			errs = append(errs, newRowError(i, &rows[i], "", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LoadJSON is like Load but takes a JSON fixture, an array of rows with
+// the same structure and markers as YAML fixtures
+func LoadJSON(data []byte, db *sql.DB, driver string) error {
+	rows, err := parseJSONFixture(data)
+	if err != nil {
+		return err
+	}
+	_, err = new(Context).load(context.Background(), [][]Row{rows}, db, driver)
+	return err
+}
+
+// Render returns the statements loading the fixture would run, along with
+// their values, without connecting to a database
+func Render(data []byte, driver string) ([]Statement, error) {
+	c := &Context{DryRun: true}
+	if err := LoadWithContext(context.Background(), data, nil, driver, c); err != nil {
+		return nil, err
+	}
+	return c.Statements, nil
+}
+
+// TruncateAndLoad empties every table referenced by the fixture and then
+// loads it, both within a single transaction
+func TruncateAndLoad(data []byte, db *sql.DB, driver string) error {
+	return LoadWithContext(context.Background(), data, db, driver, &Context{Truncate: true})
+}
+
+// Clean deletes every row of the tables referenced by the fixture, the
+// counterpart of Load for emptying them between tests. Tables are emptied in
+// reverse order of their first row, within a single transaction
+func Clean(data []byte, db *sql.DB, driver string) error {
+	return CleanWithContext(context.Background(), data, db, driver, new(Context))
+}
+
+// CleanWithContext is like Clean but runs every query with ctx and empties
+// tables before the tables they depend on in c.DependsOn. With DryRun set
+// the DELETE statements are appended to c.Statements instead
+func CleanWithContext(ctx context.Context, data []byte, db *sql.DB, driver string, c *Context) error {
+	if err := c.checkDriver(driver); err != nil {
+		return err
+	}
+	parsed, err := parseFixtures([][]byte{data})
+	if err != nil {
+		return err
+	}
+	if err := c.validateTables(parsed); err != nil {
+		return err
+	}
+	depths, err := c.tableDepths()
+	if err != nil {
+		return err
+	}
+	queries := deleteQueries(c.orderedTables(parsed, depths), c.quoter(driver))
+
+	if c.DryRun {
+		for _, query := range queries {
+			c.Statements = append(c.Statements, newStatement(query, nil))
+		}
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := truncateTables(ctx, tx, queries); err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	return tx.Commit()
+}
+
+// LoadTx processes a YAML fixture within a transaction managed by the caller,
+// the transaction is neither committed nor rolled back
+func LoadTx(tx *sql.Tx, data []byte, driver string) error {
+	return LoadTxContext(context.Background(), tx, data, driver)
+}
+
+// LoadTxContext is like LoadTx but runs every query with ctx
+func LoadTxContext(ctx context.Context, tx *sql.Tx, data []byte, driver string) error {
+	if err := new(Context).checkDriver(driver); err != nil {
+		return err
+	}
+	parsed, err := parseFixtures([][]byte{data})
+	if err != nil {
+		return err
+	}
+	if err := new(Context).validateFixtures(parsed, driver); err != nil {
+		return err
+	}
+	_, err = new(Context).loadTx(ctx, tx, parsed, driver, new(Result))
+	return err
+}
+
+// LoadNoTx is like Load but runs every query directly on db without a
+// transaction, each one committing on its own. A failing row leaves the rows
+// loaded before it in the database
+func LoadNoTx(data []byte, db *sql.DB, driver string) error {
+	if err := new(Context).checkDriver(driver); err != nil {
+		return err
+	}
+	parsed, err := parseFixtures([][]byte{data})
+	if err != nil {
+		return err
+	}
+	if err := new(Context).validateFixtures(parsed, driver); err != nil {
+		return err
+	}
+	_, err = new(Context).loadTx(context.Background(), db, parsed, driver, new(Result))
+	return err
+}
+
+// queryer runs queries, either within a transaction or directly on a
+// database
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// load inserts/updates the rows of several parsed fixtures within a single
+// transaction, so either all of them are loaded or none
+func (c *Context) load(ctx context.Context, fixtures [][]Row, db *sql.DB, driver string) (Result, error) {
+	// Reject rows which cannot be loaded before running any query
+	if err := c.checkDriver(driver); err != nil {
+		return Result{}, err
+	}
+	if err := c.validateFixtures(fixtures, driver); err != nil {
+		return Result{}, err
+	}
+
+	// Only collect the statements without touching the database
+	if c.DryRun {
+		return Result{}, c.render(fixtures, driver)
+	}
+
+	// Run the whole transaction again while it fails with transient errors
+	for attempt := 1; ; attempt++ {
+		result, failed, err := c.loadAttempt(ctx, fixtures, db, driver)
+		if err == nil {
+			// Report the rows skipped with ContinueOnError
+			return result, errors.Join(failed...)
+		}
+		if attempt >= c.Retry.MaxAttempts || !c.Retry.retryable(err) {
+			return Result{}, err
+		}
+		timer := time.NewTimer(c.Retry.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Result{}, err
+		case <-timer.C:
+		}
+	}
+}
+
+// loadAttempt loads parsed fixtures within a new transaction, the errors of
+// rows skipped with ContinueOnError are returned in failed
+func (c *Context) loadAttempt(ctx context.Context, fixtures [][]Row, db *sql.DB, driver string) (result Result, failed []error, err error) {
+	// Begin a transaction
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, nil, err
+	}
+
+	failed, err = c.loadTx(ctx, tx, fixtures, driver, &result)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return Result{}, nil, err
+	}
+
+	// Leave nothing behind once every row loaded
+	if c.Rollback {
+		if err := tx.Rollback(); err != nil {
+			return Result{}, nil, err
+		}
+		return result, failed, nil
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		tx.Rollback() // rollback the transaction
+		return Result{}, nil, err
+	}
+	return result, failed, nil
+}
+
+// loadTx processes parsed fixtures within an already open transaction, or
+// directly on a database, it neither commits nor rolls back the transaction.
+// The errors of rows skipped with ContinueOnError are returned in failed, the
+// rows are counted in result
+func (c *Context) loadTx(ctx context.Context, tx queryer, fixtures [][]Row, driver string, result *Result) (failed []error, err error) {
+	// Order of the tables the rows are loaded in
+	depths, err := c.tableDepths()
+	if err != nil {
+		return nil, err
+	}
+
+	// Check foreign keys once all the rows are loaded, the checks are
+	// restored even when the load fails as MySQL keeps them per connection
+	if deferQuery, restoreQuery := deferConstraintsQueries(driver); c.DeferConstraints && deferQuery != "" {
+		if _, err := tx.ExecContext(ctx, deferQuery); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err == nil && (driver == sqliteDriver || driver == sqlite3Driver) {
+				err = checkSQLiteForeignKeys(ctx, tx)
+			}
+			_, restoreErr := tx.ExecContext(ctx, restoreQuery)
+			if restoreErr != nil && err == nil {
+				err = restoreErr
+			}
 			if err != nil {
-				tx.Rollback() // rollback the transaction
-				return NewProcessingError(i+1, err)
+				failed = nil
+			}
+		}()
+	}
+
+	// Empty the tables before any row gets loaded
+	if c.Truncate {
+		if err := truncateTables(ctx, tx, truncateQueries(c.orderedTables(fixtures, depths), driver, c.quoter(driver))); err != nil {
+			return nil, err
+		}
+	}
+
+	progress := &progress{total: countRows(fixtures)}
+	for _, rows := range fixtures {
+		rowsFailed, err := c.loadRows(ctx, tx, rows, depths, driver, result, progress)
+		if err != nil {
+			return nil, err
+		}
+		failed = append(failed, rowsFailed...)
+	}
+
+	// Explicit primary key values may have overtaken the sequences
+	if driver == postgresDriver && !c.SkipSequenceFix {
+		if err := c.fixPostgresPKSequences(ctx, tx, fixtures); err != nil {
+			return nil, err
+		}
+	}
+
+	return failed, nil
+}
+
+// parseFixtures unmarshals the YAML data of every fixture into a []Row slice
+func parseFixtures(fixtures [][]byte) ([][]Row, error) {
+	parsed := make([][]Row, len(fixtures))
+	for i, data := range fixtures {
+		rows, err := parseFixture(data)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = rows
+	}
+	return parsed, nil
+}
+
+// parseFixture unmarshals the YAML data of a fixture into a []Row slice, the
+// rows of every document in the data are returned in order
+func parseFixture(data []byte) ([]Row, error) {
+	var rows []Row
+	for _, document := range splitDocuments(data) {
+		var documentRows []Row
+		if err := yaml.Unmarshal(document, &documentRows); err != nil {
+			return nil, err
+		}
+		rows = append(rows, documentRows...)
+	}
+	return rows, nil
+}
+
+// parseJSONFixture unmarshals the JSON data of a fixture into a []Row slice.
+// JSON numbers become int64 values when integral and float64 otherwise, so
+// integer keys are not bound as floats
+func parseJSONFixture(data []byte) ([]Row, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var rows []Row
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		normaliseJSONNumbers(row.PK)
+		normaliseJSONNumbers(row.Fields)
+	}
+	return rows, nil
+}
+
+// normaliseJSONNumbers replaces the json.Number values of m
+func normaliseJSONNumbers(m map[string]interface{}) {
+	for key, value := range m {
+		m[key] = normaliseJSONNumber(value)
+	}
+}
+
+// normaliseJSONNumber returns a json.Number value as an int64 when integral
+// and a float64 otherwise, other values are returned as is
+func normaliseJSONNumber(value interface{}) interface{} {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if i, err := number.Int64(); err == nil {
+		return i
+	} else if f, err := number.Float64(); err == nil {
+		return f
+	}
+	return value
+}
+
+// splitDocuments splits YAML data at its "---" document start markers and
+// "..." document end markers. Directives such as %YAML 1.1 are kept with the
+// document they come before, along with its "---" marker
+func splitDocuments(data []byte) [][]byte {
+	var (
+		documents [][]byte
+		start     int
+	)
+	for offset := 0; offset < len(data); {
+		end := bytes.IndexByte(data[offset:], '\n') + 1
+		if end == 0 {
+			end = len(data) - offset
+		}
+		line := bytes.TrimRight(data[offset:offset+end], "\r\n")
+		// A "---" marker following directives is part of their document
+		marker := isDocumentMarker(line, "---") && !hasDirectives(data[start:offset])
+		if marker || isDocumentMarker(line, "...") {
+			// Anything following a "---" marker belongs to the next document
+			documents = append(documents, data[start:offset])
+			start = offset + 3
+		}
+		offset += end
+	}
+	return append(documents, data[start:])
+}
+
+// hasDirectives reports whether data holds directives, such as %YAML 1.1,
+// and nothing else but blank lines and comments
+func hasDirectives(data []byte) bool {
+	directives := false
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		switch {
+		case bytes.HasPrefix(line, []byte("%")):
+			directives = true
+		case len(line) > 0 && line[0] != '#':
+			return false
+		}
+	}
+	return directives
+}
+
+// isDocumentMarker reports whether line starts with the marker followed by
+// nothing but whitespace or a comment
+func isDocumentMarker(line []byte, marker string) bool {
+	if !bytes.HasPrefix(line, []byte(marker)) {
+		return false
+	}
+	rest := line[len(marker):]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// quoter returns the function escaping identifiers for driver
+func (c *Context) quoter(driver string) func(string) string {
+	if c.Quote != nil {
+		return c.Quote
+	}
+	if c.UnquotedIdentifiers {
+		return unquotedIdentifier
+	}
+	if c.Dialect != nil {
+		return c.Dialect.Quote
+	}
+	return driverQuoter(driver)
+}
+
+// buildUpdate returns the UPDATE query of row along with its values
+func (c *Context) buildUpdate(row *Row, driver string) (string, []interface{}) {
+	if c.ReuseArgs {
+		return buildUpdateReusingArgs(row, driver)
+	}
+	return BuildUpdate(row, driver)
+}
+
+// validateFixtures validates every row of the parsed fixtures for driver,
+// including the variables they use and those of their table names
+func (c *Context) validateFixtures(fixtures [][]Row, driver string) error {
+	for _, rows := range fixtures {
+		for i := range rows {
+			if rows[i].empty() || rows[i].comment() {
+				continue
+			}
+			if err := rows[i].Validate(); err != nil {
+				return newRowError(i, &rows[i], "", err)
 			}
-			if driver == postgresDriver && row.GetInsertColumns()[0] == "\"id\"" {
-				err = fixPostgresPKSequence(tx, row.Table, "id")
+			if err := rows[i].validateVars(c.Vars); err != nil {
+				return newRowError(i, &rows[i], "", err)
+			}
+			if err := c.checkReturning(&rows[i], driver); err != nil {
+				return newRowError(i, &rows[i], "", err)
+			}
+		}
+	}
+	return c.validateTables(fixtures)
+}
+
+// validateTables checks the {name} variables of the table name of every row
+// of the parsed fixtures refer to one of c.Vars
+func (c *Context) validateTables(fixtures [][]Row) error {
+	for _, rows := range fixtures {
+		for i := range rows {
+			if rows[i].empty() || rows[i].comment() {
+				continue
+			}
+			if _, err := rows[i].resolveTable(c.Vars); err != nil {
+				return newRowError(i, &rows[i], "", err)
+			}
+		}
+	}
+	return nil
+}
+
+// table returns the table name of row with its {name} variables resolved.
+// The rows of the caller keep their template, so they can be loaded again
+// with other variables. Names which cannot be resolved are returned as is,
+// validateTables reports them
+func (c *Context) table(row *Row) string {
+	table, err := row.resolveTable(c.Vars)
+	if err != nil {
+		return row.Table
+	}
+	return table
+}
+
+// initRow loads the internal variables of row with the options of c, for
+// queries of driver. The table name of row is resolved, row has to be a copy
+// of the row of the caller
+func (c *Context) initRow(row *Row, driver string) {
+	row.Table = c.table(row)
+	row.newUUID = c.NewUUID
+	row.now = c.Now
+	row.quote = c.Quote
+	if row.quote == nil && c.UnquotedIdentifiers {
+		row.quote = unquotedIdentifier
+	}
+	row.dialect = c.Dialect
+	row.vars = c.Vars
+	row.parseTimestamps = c.ParseTimestamps
+	row.columnMap = c.ColumnMap
+	row.complexDriver = ""
+	if c.SerializeComplex {
+		row.complexDriver = driver
+	}
+	row.Init()
+}
+
+// transformValues replaces the values the i-th row of a fixture binds with
+// those returned by ValueTransformer. A column is transformed once, its value
+// is used for every query of the row. The values of markers, such as NULL()
+// or VAR(name), are left untouched
+func (c *Context) transformValues(i int, row *Row) error {
+	if c.ValueTransformer == nil {
+		return nil
+	}
+	markers := make(map[string]bool)
+	for name, value := range row.PK {
+		markers[row.column(name)] = isMarker(value)
+	}
+	for _, field := range row.fields() {
+		markers[row.column(field.Name)] = isMarker(field.Value)
+	}
+	transformed := make(map[string]interface{})
+	transform := func(columns []string, values []interface{}) error {
+		for k, column := range columns {
+			if markers[column] {
+				continue
+			}
+			value, ok := transformed[column]
+			if !ok {
+				var err error
+				value, err = c.ValueTransformer(row.Table, column, values[k])
 				if err != nil {
-					tx.Rollback()
-					return NewProcessingError(i+1, err)
+					return newRowError(i, row, column, err)
 				}
+				transformed[column] = value
 			}
-		} else {
-			// Primary key found, let's run UPDATE query
-			updateQuery := fmt.Sprintf(
-				`UPDATE "%s" SET %s WHERE %s`,
-				row.Table,
-				strings.Join(row.GetUpdatePlaceholders(driver), ", "),
-				row.GetWhere(driver, row.GetUpdateColumnsLength()),
-			)
-			values := append(row.GetUpdateValues(), row.GetPKValues()...)
-			_, err := tx.Exec(updateQuery, values...)
-			if err != nil {
-				tx.Rollback() // rollback the transaction
-				return NewProcessingError(i+1, err)
+			values[k] = value
+		}
+		return nil
+	}
+	if err := transform(row.pkColumns, row.pkValues); err != nil {
+		return err
+	}
+	if err := transform(row.matchColumns, row.matchValues); err != nil {
+		return err
+	}
+	if err := transform(row.insertColumns, row.insertValues); err != nil {
+		return err
+	}
+	return transform(row.updateColumns, row.updateValues)
+}
+
+// render appends the statements loading fixtures would run to c.Statements
+// without executing any of them. Whether a row exists is not known, so both
+// the INSERT and the UPDATE it may need are recorded after its SELECT, or
+// only the INSERT of rows always inserted
+func (c *Context) render(fixtures [][]Row, driver string) error {
+	depths, err := c.tableDepths()
+	if err != nil {
+		return err
+	}
+
+	deferQuery, restoreQuery := deferConstraintsQueries(driver)
+	if c.DeferConstraints && deferQuery != "" {
+		c.Statements = append(c.Statements, Statement{Query: deferQuery})
+	}
+
+	if c.Truncate {
+		for _, query := range truncateQueries(c.orderedTables(fixtures, depths), driver, c.quoter(driver)) {
+			c.Statements = append(c.Statements, Statement{Query: query})
+		}
+	}
+
+	for _, rows := range fixtures {
+		for _, i := range c.rowOrder(rows, depths) {
+			if rows[i].comment() {
+				continue
 			}
-			if driver == postgresDriver && row.GetUpdateColumns()[0] == "\"id\"" {
-				err = fixPostgresPKSequence(tx, row.Table, "id")
-				if err != nil {
-					tx.Rollback()
-					return NewProcessingError(i+1, err)
+			if rows[i].empty() {
+				c.logf("Skipping empty row %d", i+1)
+				continue
+			}
+			if rows[i].guarded(c.Vars) {
+				continue
+			}
+			row := rows[i]
+			c.initRow(&row, driver)
+			if err := c.transformValues(i, &row); err != nil {
+				return err
+			}
+
+			mode := c.tableMode(row.Table)
+			switch {
+			case row.Delete:
+				c.Statements = append(c.Statements, newStatement(BuildDelete(&row, driver)))
+			case mode == ModeUpsert && supportsUpsert(driver):
+				c.Statements = append(c.Statements, newStatement(BuildUpsert(&row, driver)))
+			case mode == ModeInsertOnly:
+				c.Statements = append(c.Statements, newStatement(BuildInsert(&row, driver)))
+			default:
+				if c.SkipUnchanged {
+					c.Statements = append(c.Statements, newStatement(buildExistsUnchanged(&row, driver)))
+				} else {
+					c.Statements = append(c.Statements, newStatement(BuildExists(&row, driver)))
+				}
+				if mode != ModeUpdateOnly {
+					c.Statements = append(c.Statements, newStatement(BuildInsert(&row, driver)))
+				}
+				if row.GetUpdateColumnsLength() > 0 {
+					c.Statements = append(c.Statements, newStatement(c.buildUpdate(&row, driver)))
 				}
 			}
 		}
 	}
 
-	// Commit the transaction
-	if err := tx.Commit(); err != nil {
-		tx.Rollback() // rollback the transaction
+	if c.DeferConstraints && restoreQuery != "" {
+		c.Statements = append(c.Statements, Statement{Query: restoreQuery})
+	}
+
+	return nil
+}
+
+// loadRows inserts/updates the rows of a single fixture, with ContinueOnError
+// set a failing row is skipped and its error returned in failed instead
+func (c *Context) loadRows(ctx context.Context, tx queryer, rows []Row, depths map[string]int, driver string, result *Result, progress *progress) (failed []error, err error) {
+	// New rows waiting to be inserted together when batching
+	batch := &insertBatch{copy: c.Copy && driver == postgresDriver, maxArgs: c.MaxArgs}
+	if batch.maxArgs == 0 {
+		batch.maxArgs = maxArgs(driver)
+	}
+
+	saveQuery, rollbackQuery, releaseQuery := savepointQueries(driver, "fixtures_row")
+
+	// Iterate over rows define in the fixture, parent tables first
+	for _, i := range c.rowOrder(rows, depths) {
+		if rows[i].comment() {
+			continue
+		}
+		if rows[i].empty() {
+			c.logf("Skipping empty row %d", i+1)
+			continue
+		}
+		if rows[i].guarded(c.Vars) {
+			c.reportProgress(progress)
+			continue
+		}
+		row := rows[i]
+
+		// Load internat struct variables
+		c.initRow(&row, driver)
+
+		if !c.ContinueOnError {
+			if err := c.loadRow(ctx, tx, i, &row, batch, driver, result); err != nil {
+				return nil, err
+			}
+			rows[i].returned = row.returned
+			c.reportProgress(progress)
+			continue
+		}
+
+		// Undo whatever a failing row did and carry on with the next one
+		if _, err := tx.ExecContext(ctx, saveQuery); err != nil {
+			return nil, err
+		}
+		if err := c.loadRow(ctx, tx, i, &row, batch, driver, result); err != nil {
+			failed = append(failed, err)
+			result.Skipped++
+			if _, err := tx.ExecContext(ctx, rollbackQuery); err != nil {
+				return nil, err
+			}
+		} else {
+			rows[i].returned = row.returned
+		}
+		c.reportProgress(progress)
+		if releaseQuery == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, releaseQuery); err != nil {
+			return nil, err
+		}
+	}
+
+	return failed, batch.flush(ctx, tx, driver)
+}
+
+// loadRow writes the i-th row of a fixture between the BeforeRow and
+// AfterRow hooks
+func (c *Context) loadRow(ctx context.Context, tx queryer, i int, row *Row, batch *insertBatch, driver string, result *Result) error {
+	if c.BeforeRow != nil {
+		c.BeforeRow(row)
+	}
+	err := c.writeRow(ctx, tx, i, row, batch, driver, result)
+	if c.AfterRow != nil {
+		c.AfterRow(row, err)
+	}
+	return err
+}
+
+// writeRow inserts/updates/deletes the i-th row of a fixture and counts it in
+// result, new rows are added to batch rather than inserted when batching
+func (c *Context) writeRow(ctx context.Context, tx queryer, i int, row *Row, batch *insertBatch, driver string, result *Result) error {
+	if err := c.transformValues(i, row); err != nil {
+		return err
+	}
+
+	mode := c.tableMode(row.Table)
+	upsert := mode == ModeUpsert && supportsUpsert(driver)
+
+	// Rows are written in fixture order, so anything which is not a new
+	// row joining the batch has to wait for the batch to be inserted
+	if row.Delete || upsert || batch.contains(row) {
+		if err := batch.flush(ctx, tx, driver); err != nil {
+			return err
+		}
+	}
+
+	if row.Delete {
+		// Row marked for deletion, a missing row is simply left alone
+		query, values := BuildDelete(row, driver)
+		_, err := tx.ExecContext(ctx, query, values...)
+		if err != nil {
+			return newQueryError(i, row, query, values, err)
+		}
+		result.Deleted++
+		return nil
+	}
+
+	if upsert {
+		// Insert the row or update it if the primary key exists
+		query, values := BuildUpsert(row, driver)
+		if err := execRow(ctx, tx, row, driver, query, values); err != nil {
+			return newQueryError(i, row, query, values, err)
+		}
+		result.Upserted++
+		return nil
+	}
+
+	// Run a SELECT query to find out if we need to insert or UPDATE
+	var exists, unchanged bool
+	if c.SkipUnchanged && mode != ModeInsertOnly {
+		query, values := buildExistsUnchanged(row, driver)
+		err := tx.QueryRowContext(ctx, query, values...).Scan(&exists, &unchanged)
+		if err != nil {
+			return newQueryError(i, row, query, values, err)
+		}
+	} else if mode != ModeInsertOnly {
+		query, values := BuildExists(row, driver)
+		err := tx.QueryRowContext(ctx, query, values...).Scan(&exists)
+		if err != nil {
+			return newQueryError(i, row, query, values, err)
+		}
+	}
+
+	// Missing rows of tables which are only updated are left out
+	if !exists && mode == ModeUpdateOnly {
+		c.logf("Row %d of table %s does not exist and is only updated, leaving it out", i+1, row.Table)
+		return nil
+	}
+
+	// A batched row would only fail when the batch is inserted, so rows are
+	// never batched when they may have to be skipped one by one, nor when
+	// columns have to be read back. Values bound by name would clash between
+	// the rows of a batch. Oracle has no multi-values INSERT
+	if !exists && c.Batch && !c.ContinueOnError && len(row.Returning) == 0 && !row.named() && driver != oracleDriver && driver != godrorDriver {
+		// Primary key not found, let's insert the row with the batch
+		if !batch.accepts(row) {
+			if err := batch.flush(ctx, tx, driver); err != nil {
+				return err
+			}
+		}
+		batch.add(i, *row)
+		result.Inserted++
+	} else if !exists {
+		if err := batch.flush(ctx, tx, driver); err != nil {
+			return err
+		}
+
+		// Primary key not found, let's run an INSERT query
+		query, values := BuildInsert(row, driver)
+		if err := execRow(ctx, tx, row, driver, query, values); err != nil {
+			return newQueryError(i, row, query, values, err)
+		}
+		result.Inserted++
+	} else if unchanged {
+		// The row already holds the values, skip the UPDATE
+		result.Unchanged++
+	} else if row.GetUpdateColumnsLength() > 0 {
+		if err := batch.flush(ctx, tx, driver); err != nil {
+			return err
+		}
+
+		// Primary key found, let's run UPDATE query
+		query, values := c.buildUpdate(row, driver)
+		if err := execRow(ctx, tx, row, driver, query, values); err != nil {
+			return newQueryError(i, row, query, values, err)
+		}
+		result.Updated++
+	} else {
+		// Every column is only written on insert, such as an identity key
+		// along with ON_INSERT_NOW() columns
+		c.logf("Row %d of table %s already exists and has no column to update, leaving it untouched", i+1, row.Table)
+	}
+
+	return nil
+}
+
+// tableMode returns how the rows of table are written, the mode set in
+// TableMode or else the one following from Upsert and AssumeEmpty
+func (c *Context) tableMode(table string) Mode {
+	if mode := c.TableMode[table]; mode != ModeDefault {
+		return mode
+	}
+	if c.Upsert {
+		return ModeUpsert
+	}
+	if c.AssumeEmpty {
+		return ModeInsertOnly
+	}
+	return ModeDefault
+}
+
+// execRow runs a query writing row, the Returning columns of the row are
+// read back into its returned values. Oracle returns them INTO output
+// parameters bound after the values rather than as a result row
+func execRow(ctx context.Context, tx queryer, row *Row, driver, query string, values []interface{}) error {
+	if len(row.Returning) == 0 {
+		_, err := tx.ExecContext(ctx, query, values...)
 		return err
 	}
 
+	returned := make([]interface{}, len(row.Returning))
+	dest := make([]interface{}, len(returned))
+	for k := range returned {
+		dest[k] = &returned[k]
+	}
+	if driver == oracleDriver || driver == godrorDriver {
+		args := append([]interface{}{}, values...)
+		for k := range dest {
+			args = append(args, sql.Out{Dest: dest[k]})
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	} else if err := tx.QueryRowContext(ctx, query, values...).Scan(dest...); err != nil {
+		return err
+	}
+	row.returned = make(map[string]interface{}, len(returned))
+	for k, column := range row.Returning {
+		row.returned[column] = returned[k]
+	}
 	return nil
 }
 
@@ -112,8 +1043,11 @@ func LoadFile(filename string, db *sql.DB, driver string) error {
 		return NewFileError(filename, err)
 	}
 
-	// Insert the fixture data
-	return Load(data, db, driver)
+	// Insert the fixture data, errors name the file of the failing row
+	if err := Load(data, db, driver); err != nil {
+		return NewFileError(filename, err)
+	}
+	return nil
 }
 
 // LoadFiles ...
@@ -126,17 +1060,187 @@ func LoadFiles(filenames []string, db *sql.DB, driver string) error {
 	return nil
 }
 
-// fixPostgresPKSequence
-func fixPostgresPKSequence(tx *sql.Tx, table string, column string) error {
-	// Query for the qualified sequence name
-	var seqName *string
-	err := tx.QueryRow(`
-		SELECT pg_get_serial_sequence($1, $2)
-	`, table, column).Scan(&seqName)
+// LoadDir loads every *.yml and *.yaml fixture found in dir in lexicographic
+// order of their names, all within a single transaction
+func LoadDir(dir string, db *sql.DB, driver string) error {
+	// Find fixture files, anything else in the directory is ignored
+	var filenames []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return err
+		}
+		filenames = append(filenames, matches...)
+	}
+	sort.Strings(filenames)
 
+	// Nothing to load in an empty directory
+	if len(filenames) == 0 {
+		return nil
+	}
+
+	// Read fixture data from the files
+	fixtures := make([][]byte, len(filenames))
+	for i, filename := range filenames {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return NewFileError(filename, err)
+		}
+		fixtures[i] = data
+	}
+
+	// Insert the fixture data
+	parsed, err := parseFixtures(fixtures)
 	if err != nil {
 		return err
 	}
+	_, err = new(Context).load(context.Background(), parsed, db, driver)
+	return err
+}
+
+// countRows returns how many rows of fixtures are loaded or skipped by their
+// when guard, empty and comment rows are not counted
+func countRows(fixtures [][]Row) int {
+	count := 0
+	for _, rows := range fixtures {
+		for i := range rows {
+			if !rows[i].empty() && !rows[i].comment() {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// progress counts the rows a load handled so far, out of total. It is kept
+// by the load rather than the Context, which loads may share
+type progress struct {
+	done  int
+	total int
+}
+
+// reportProgress counts a row of a load as done and passes the progress of
+// the load to Progress, if any
+func (c *Context) reportProgress(progress *progress) {
+	progress.done++
+	if c.Progress != nil {
+		c.Progress(progress.done, progress.total)
+	}
+}
+
+// tablesOf returns the distinct resolved tables referenced by rows, in the
+// order they first appear
+func (c *Context) tablesOf(fixtures [][]Row) []string {
+	seen := make(map[string]bool)
+	var tables []string
+	for _, rows := range fixtures {
+		for i := range rows {
+			if rows[i].empty() || rows[i].comment() {
+				continue
+			}
+			if table := c.table(&rows[i]); !seen[table] {
+				seen[table] = true
+				tables = append(tables, table)
+			}
+		}
+	}
+	return tables
+}
+
+// orderedTables returns the distinct resolved tables referenced by rows in
+// the order they are loaded, the tables of c.DependsOn after the tables they
+// depend on and otherwise in the order they first appear. Emptying them in
+// reverse order empties children before their parents
+func (c *Context) orderedTables(fixtures [][]Row, depths map[string]int) []string {
+	tables := c.tablesOf(fixtures)
+	sort.SliceStable(tables, func(a, b int) bool {
+		return depths[tables[a]] < depths[tables[b]]
+	})
+	return tables
+}
+
+// truncateTables runs the queries emptying tables within the transaction
+func truncateTables(ctx context.Context, tx queryer, queries []string) error {
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSQLiteForeignKeys reports a row violating a foreign key. A COMMIT
+// failing on deferred foreign keys would leave the transaction open, so
+// they are checked before the transaction is committed
+func checkSQLiteForeignKeys(ctx context.Context, tx queryer) error {
+	var (
+		table  string
+		rowID  sql.NullInt64
+		parent string
+		fkID   int
+	)
+	err := tx.QueryRowContext(ctx, "PRAGMA foreign_key_check").Scan(&table, &rowID, &parent, &fkID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("FOREIGN KEY constraint failed: row %d of table %s references a missing %s row", rowID.Int64, table, parent)
+}
+
+// fixPostgresPKSequences fixes the sequences of the primary key columns of
+// every table the fixtures load rows into, once per table and column rather
+// than once per row. Columns without a sequence are left alone
+func (c *Context) fixPostgresPKSequences(ctx context.Context, tx queryer, fixtures [][]Row) error {
+	quote := c.quoter(postgresDriver)
+	seen := make(map[[2]string]bool)
+	for _, rows := range fixtures {
+		for i := range rows {
+			if rows[i].Delete || rows[i].comment() {
+				continue
+			}
+			row := rows[i]
+			row.Table = c.table(&row)
+			columns := make([]string, 0, len(row.PK))
+			for column := range row.PK {
+				if mapped, ok := c.ColumnMap[column]; ok {
+					column = mapped
+				}
+				columns = append(columns, column)
+			}
+			sort.Strings(columns)
+			for _, column := range columns {
+				key := [2]string{row.Table, column}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				sequence := c.Sequences[row.Table+"."+column]
+				if err := fixPostgresPKSequence(ctx, tx, row.Table, column, sequence, quote); err != nil {
+					return fmt.Errorf("Error fixing sequence of %s.%s: %w", row.Table, column, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fixPostgresPKSequence sets the sequence of a serial column, if it has one,
+// to the greatest value of the column, names are quoted with quote. An
+// empty sequence is looked up
+func fixPostgresPKSequence(ctx context.Context, tx queryer, table string, column string, sequence string, quote func(string) string) error {
+	// Query for the qualified sequence name, unless it is given
+	seqName := &sequence
+	if sequence == "" {
+		err := tx.QueryRowContext(ctx, `
+			SELECT pg_get_serial_sequence($1, $2)
+		`, quoteTable(quote, table), column).Scan(&seqName)
+
+		if err != nil {
+			return err
+		}
+	}
 
 	if seqName == nil {
 		// No sequence to fix
@@ -144,9 +1248,54 @@ func fixPostgresPKSequence(tx *sql.Tx, table string, column string) error {
 	}
 
 	// Set the sequence
-	_, err = tx.Exec(fmt.Sprintf(`
-		SELECT pg_catalog.setval($1, (SELECT MAX("%s") FROM "%s"))
-	`, column, table), *seqName)
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		SELECT pg_catalog.setval($1, (SELECT MAX(%s) FROM %s))
+	`, quote(column), quoteTable(quote, table)), *seqName)
+
+	return err
+}
+
+// LoadFS loads every file of fsys matching one of the glob patterns, such as
+// fixtures bundled with go:embed, in lexicographic order of their paths and
+// all within a single transaction
+func LoadFS(fsys fs.FS, patterns []string, db *sql.DB, driver string) error {
+	// Find fixture files, a file matching several patterns is only loaded once
+	seen := make(map[string]bool)
+	var filenames []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				filenames = append(filenames, match)
+			}
+		}
+	}
+	sort.Strings(filenames)
+
+	// Nothing to load when no file matches
+	if len(filenames) == 0 {
+		return nil
+	}
+
+	// Read fixture data from the files
+	fixtures := make([][]byte, len(filenames))
+	for i, filename := range filenames {
+		data, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return NewFileError(filename, err)
+		}
+		fixtures[i] = data
+	}
 
+	// Insert the fixture data
+	parsed, err := parseFixtures(fixtures)
+	if err != nil {
+		return err
+	}
+	_, err = new(Context).load(context.Background(), parsed, db, driver)
 	return err
 }
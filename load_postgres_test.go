@@ -1,6 +1,7 @@
 package fixtures
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -481,6 +482,151 @@ func TestLoadFilesFailsWithABadFilePostgres(t *testing.T) {
 	assert.EqualError(t, err, "Error loading file bad_file: open bad_file: no such file or directory")
 }
 
+func TestTruncateAndLoadEmptiesTablesPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaPostgres)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Add rows which are not part of the fixture
+	_, err = db.Exec(`INSERT INTO some_table(id, string_field, boolean_field) VALUES(10, 'stale', false)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO join_table(some_id, other_id) VALUES(10, 20)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Let's truncate the tables and load the fixture
+	err = TruncateAndLoad([]byte(testData), db, "postgres")
+
+	// Error should be nil
+	assert.Nil(t, err)
+
+	var count int
+
+	// Only the fixture rows should be left
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM some_table WHERE id = 10").Scan(&count)
+	assert.Equal(t, 0, count)
+	db.QueryRow("SELECT COUNT(*) FROM join_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM join_table WHERE some_id = 10").Scan(&count)
+	assert.Equal(t, 0, count)
+}
+
+func TestLoadWithUpsertPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaPostgres)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var (
+		count     int
+		createdAt *time.Time
+		updatedAt *time.Time
+	)
+
+	// The first load inserts the rows
+	err = LoadWithContext(context.Background(), []byte(testData), db, "postgres", &Context{Upsert: true})
+	assert.Nil(t, err)
+	db.QueryRow("SELECT created_at, updated_at FROM some_table WHERE id = 1").Scan(&createdAt, &updatedAt)
+	assert.NotNil(t, createdAt)
+	assert.Nil(t, updatedAt)
+
+	// The second load updates them
+	err = LoadWithContext(context.Background(), []byte(testData), db, "postgres", &Context{Upsert: true})
+	assert.Nil(t, err)
+	db.QueryRow("SELECT created_at, updated_at FROM some_table WHERE id = 1").Scan(&createdAt, &updatedAt)
+	assert.NotNil(t, createdAt)
+	assert.NotNil(t, updatedAt)
+
+	// Check row counts, should be unchanged
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM join_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadWithUpsertOnConflictColumnsPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema with a unique business key
+	_, err = db.Exec(`
+CREATE TABLE accounts(
+  id INT PRIMARY KEY NOT NULL,
+  code VARCHAR(50) NOT NULL UNIQUE,
+  name VARCHAR(50) NOT NULL
+);
+INSERT INTO accounts(id, code, name) VALUES(10, 'acme', 'Old name');
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The row conflicts on its code rather than its primary key
+	err = LoadWithContext(context.Background(), []byte(`
+---
+- table: 'accounts'
+  pk:
+    id: 1
+  fields:
+    code: 'acme'
+    name: 'Acme'
+  conflict_columns: ['code']
+`), db, "postgres", &Context{Upsert: true})
+	assert.Nil(t, err)
+
+	var (
+		count int
+		id    int
+		name  string
+	)
+	db.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT id, name FROM accounts WHERE code = 'acme'").Scan(&id, &name)
+	assert.Equal(t, 1, id)
+	assert.Equal(t, "Acme", name)
+}
+
 // rebuildDatabase attempts to delete an existing Postgres
 // database and rebuild it, returning a pointer to it
 func rebuildDatabasePostgres(dbUser, dbName string) (*sql.DB, error) {
@@ -523,3 +669,668 @@ func dropPostgresDB(dbUser, dbName string) {
 	fmt.Println(dropDbCmd)
 	exec.Command("sh", "-c", dropDbCmd).Output()
 }
+
+func TestLoadWorksWithSchemaQualifiedTablePostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table outside of the public schema
+	_, err = db.Exec(`
+CREATE SCHEMA billing;
+CREATE TABLE billing.invoices(
+  id SERIAL PRIMARY KEY,
+  amount INT NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+---
+- table: 'billing.invoices'
+  pk:
+    id: 1
+  fields:
+    amount: 100
+`)
+
+	var count int
+
+	// Let's load the fixture twice, inserting and then updating the row
+	err = Load(data, db, "postgres")
+	assert.Nil(t, err)
+	err = Load(data, db, "postgres")
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM billing.invoices").Scan(&count)
+	assert.Equal(t, 1, count)
+
+	// The sequence of the schema qualified table has been fixed
+	_, err = db.Exec("INSERT INTO billing.invoices(amount) VALUES(200)")
+	assert.Nil(t, err)
+}
+
+func TestLoadFixesSequenceOfAnyPKColumnPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table whose serial primary key is not called id
+	_, err = db.Exec(`
+CREATE TABLE users(
+  user_id SERIAL PRIMARY KEY,
+  name VARCHAR(50) NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = Load([]byte(`
+---
+- table: 'users'
+  pk:
+    user_id: 5
+  fields:
+    name: 'Foo'
+`), db, "postgres")
+	assert.Nil(t, err)
+
+	// The next generated key follows the loaded one
+	var userID int
+	err = db.QueryRow("INSERT INTO users(name) VALUES('Bar') RETURNING user_id").Scan(&userID)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, userID)
+}
+
+func BenchmarkLoadSerialPKPostgres(b *testing.B) {
+	// Connect to a test Postgres db
+	db, err := rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table with a sequence to fix
+	_, err = db.Exec(`
+CREATE TABLE users(
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(50) NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// A fixture with many rows of the same table, its sequence only has to
+	// be fixed once rather than after every row
+	var data []byte
+	for i := 1; i <= 300; i++ {
+		data = append(data, fmt.Sprintf(
+			"- table: 'users'\n  pk:\n    id: %d\n  fields:\n    name: 'foobar'\n",
+			i,
+		)...)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			log.Fatal(err)
+		}
+		b.StartTimer()
+
+		if err := Load(data, db, "postgres"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestLoadWithDeferConstraintsPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table referencing itself with a deferrable foreign key
+	_, err = db.Exec(`
+CREATE TABLE users(
+  id INT PRIMARY KEY NOT NULL,
+  best_friend_id INT NOT NULL REFERENCES users(id) DEFERRABLE
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Each user references the other
+	data := []byte(`
+---
+- table: 'users'
+  pk:
+    id: 1
+  fields:
+    best_friend_id: 2
+- table: 'users'
+  pk:
+    id: 2
+  fields:
+    best_friend_id: 1
+`)
+
+	var count int
+
+	// No order satisfies immediate checks
+	err = Load(data, db, "postgres")
+	assert.NotNil(t, err)
+
+	// Deferred checks only run once both rows exist
+	err = LoadWithContext(context.Background(), data, db, "postgres", &Context{DeferConstraints: true})
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	assert.Equal(t, 2, count)
+}
+
+func TestLoadWithReuseArgsPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaPostgres)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var updatedAt *time.Time
+
+	// The second load runs the updates with reused placeholders
+	c := &Context{ReuseArgs: true}
+	err = LoadWithContext(context.Background(), []byte(testData), db, "postgres", c)
+	assert.Nil(t, err)
+	err = LoadWithContext(context.Background(), []byte(testData), db, "postgres", c)
+	assert.Nil(t, err)
+	db.QueryRow("SELECT updated_at FROM some_table WHERE id = 1").Scan(&updatedAt)
+	assert.NotNil(t, updatedAt)
+}
+
+func TestLoadWithReuseArgsAndVarsPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaPostgres)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var data = []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 'VAR(uid)'
+  fields:
+    string_field: 'VAR(name)'
+    boolean_field: true
+  update_columns: [string_field]
+`)
+
+	var stringField string
+
+	// The update looks the row up by the value of the variable
+	c := &Context{ReuseArgs: true, Vars: map[string]interface{}{"uid": 1, "name": "foo"}}
+	err = LoadWithContext(context.Background(), data, db, "postgres", c)
+	assert.Nil(t, err)
+	c.Vars["name"] = "bar"
+	err = LoadWithContext(context.Background(), data, db, "postgres", c)
+	assert.Nil(t, err)
+	db.QueryRow("SELECT string_field FROM some_table WHERE id = 1").Scan(&stringField)
+	assert.Equal(t, "bar", stringField)
+}
+
+func TestLoadRowsWithReturningPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table with a generated primary key and a defaulted column
+	_, err = db.Exec(`
+CREATE TABLE users(
+  id SERIAL PRIMARY KEY,
+  email VARCHAR(50) NOT NULL UNIQUE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rows := []Row{{
+		Table:     "users",
+		MatchOn:   []string{"email"},
+		Fields:    map[string]interface{}{"email": "foo@example.com"},
+		Returning: []string{"id", "created_at"},
+	}}
+
+	// Both generated columns are read back
+	err = LoadRows(context.Background(), rows, db, "postgres", new(Context))
+	assert.Nil(t, err)
+	returned := rows[0].Returned()
+	assert.Equal(t, int64(1), returned["id"])
+	assert.IsType(t, time.Time{}, returned["created_at"])
+
+	// Updating the row reads them back too
+	err = LoadRows(context.Background(), rows, db, "postgres", new(Context))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), rows[0].Returned()["id"])
+}
+
+func TestLoadWithCopyPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table for the copied rows and one for the inserted rows
+	for _, table := range []string{"copied", "inserted"} {
+		_, err = db.Exec(fmt.Sprintf(`
+CREATE TABLE %s(
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(50),
+  active BOOLEAN NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE
+);
+`, table))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// fixture returns the same rows for table
+	fixture := func(table string) []byte {
+		return []byte(fmt.Sprintf(`
+---
+- table: '%[1]s'
+  pk:
+    id: 1
+  fields:
+    name: 'foo, "bar"'
+    active: true
+    created_at: '2023-01-02T15:04:05Z'
+- table: '%[1]s'
+  pk:
+    id: 2
+  fields:
+    name: 'NULL()'
+    active: false
+    created_at: 'ON_INSERT_NOW()'
+`, table))
+	}
+
+	err = LoadWithContext(context.Background(), fixture("copied"), db, "postgres", &Context{Batch: true, Copy: true})
+	assert.Nil(t, err)
+	err = LoadWithContext(context.Background(), fixture("inserted"), db, "postgres", &Context{Batch: true})
+	assert.Nil(t, err)
+
+	// Both ways load the same rows
+	var count int
+	db.QueryRow(`
+		SELECT COUNT(*) FROM copied c JOIN inserted i ON i.id = c.id
+		AND i.name IS NOT DISTINCT FROM c.name AND i.active = c.active
+		AND (i.created_at = c.created_at OR c.id = 2)
+	`).Scan(&count)
+	assert.Equal(t, 2, count)
+
+	// The sequence is fixed after copying too
+	var id int
+	err = db.QueryRow("INSERT INTO copied(active) VALUES(true) RETURNING id").Scan(&id)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, id)
+}
+
+func BenchmarkLoadWithCopyPostgres(b *testing.B) {
+	// Connect to a test Postgres db
+	db, err := rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+CREATE TABLE users(
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(50) NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// A fixture with many new rows of the same table
+	var data []byte
+	for i := 1; i <= 5000; i++ {
+		data = append(data, fmt.Sprintf(
+			"- table: 'users'\n  pk:\n    id: %d\n  fields:\n    name: 'foobar'\n",
+			i,
+		)...)
+	}
+
+	for _, c := range []struct {
+		name    string
+		context Context
+	}{
+		{"Insert", Context{Batch: true, AssumeEmpty: true}},
+		{"Copy", Context{Batch: true, AssumeEmpty: true, Copy: true}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				if _, err := db.Exec("DELETE FROM users"); err != nil {
+					log.Fatal(err)
+				}
+				b.StartTimer()
+
+				options := c.context
+				if err := LoadWithContext(context.Background(), data, db, "postgres", &options); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFixesSequenceOverridePostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table using a sequence it does not own, which
+	// pg_get_serial_sequence cannot find
+	_, err = db.Exec(`
+CREATE SEQUENCE user_ids;
+CREATE TABLE users(
+  id INT PRIMARY KEY DEFAULT nextval('user_ids'),
+  name VARCHAR(50) NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c := &Context{Sequences: map[string]string{"users.id": "user_ids"}}
+	err = LoadWithContext(context.Background(), []byte(`
+---
+- table: 'users'
+  pk:
+    id: 5
+  fields:
+    name: 'Foo'
+`), db, "postgres", c)
+	assert.Nil(t, err)
+
+	// The next generated key follows the loaded one
+	var userID int
+	err = db.QueryRow("INSERT INTO users(name) VALUES('Bar') RETURNING id").Scan(&userID)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, userID)
+}
+
+func TestLoadIdentityAlwaysPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table whose primary key is always generated
+	_, err = db.Exec(`
+CREATE TABLE users(
+  id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  name VARCHAR(50) NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+---
+- table: 'users'
+  pk:
+    id: 5
+  fields:
+    name: 'Foo'
+  override_system_value: true
+`)
+
+	// The row is inserted and then updated
+	for i := 0; i < 2; i++ {
+		err = Load(data, db, "postgres")
+		assert.Nil(t, err)
+	}
+
+	var name string
+	db.QueryRow("SELECT name FROM users WHERE id = 5").Scan(&name)
+	assert.Equal(t, "Foo", name)
+}
+
+func TestLoadIntoTemporaryTablePostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Temporary tables only exist on the connection creating them
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+CREATE TEMPORARY TABLE users(
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(50) NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+---
+- table: 'users'
+  pk:
+    id: 5
+  fields:
+    name: 'Foo'
+`)
+
+	var userID int
+
+	// The sequence of a temporary table is fixed too
+	err = Load(data, db, "postgres")
+	assert.Nil(t, err)
+	err = db.QueryRow("INSERT INTO users(name) VALUES('Bar') RETURNING id").Scan(&userID)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, userID)
+
+	// Unless it is skipped
+	_, err = db.Exec("TRUNCATE TABLE users RESTART IDENTITY")
+	assert.Nil(t, err)
+	err = LoadWithContext(context.Background(), data, db, "postgres", &Context{SkipSequenceFix: true})
+	assert.Nil(t, err)
+	err = db.QueryRow("INSERT INTO users(name) VALUES('Bar') RETURNING id").Scan(&userID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, userID)
+}
+
+func TestLoadWithSerializeComplexPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table with array and jsonb columns
+	_, err = db.Exec(`
+CREATE TABLE documents(
+  id INT PRIMARY KEY NOT NULL,
+  scores INT[] NOT NULL,
+  labels TEXT[] NOT NULL,
+  meta JSONB NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = LoadWithContext(context.Background(), []byte(`
+---
+- table: 'documents'
+  pk:
+    id: 1
+  fields:
+    scores: [1, 2, 3]
+    labels: ['a', 'b "c"']
+    meta:
+      author: 'Foo'
+      tags: ['x', 'y']
+`), db, "postgres", &Context{SerializeComplex: true})
+	assert.Nil(t, err)
+
+	var (
+		scores string
+		labels string
+		author string
+		tag    string
+	)
+	db.QueryRow("SELECT scores::text, labels::text FROM documents WHERE id = 1").Scan(&scores, &labels)
+	assert.Equal(t, "{1,2,3}", scores)
+	assert.Equal(t, `{a,"b \"c\""}`, labels)
+	db.QueryRow("SELECT meta->>'author', meta->'tags'->>1 FROM documents WHERE id = 1").Scan(&author, &tag)
+	assert.Equal(t, "Foo", author)
+	assert.Equal(t, "y", tag)
+}
+
+func TestLoadBytesIntoByteaPostgres(t *testing.T) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to a test Postgres db
+	db, err = rebuildDatabasePostgres(testPostgresDbUser, testPostgresDbName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table with a bytea column
+	_, err = db.Exec(`
+CREATE TABLE files(
+  id INT PRIMARY KEY NOT NULL,
+  contents BYTEA NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = Load([]byte(`
+---
+- table: 'files'
+  pk:
+    id: 1
+  fields:
+    contents: 'BYTES(AAH/aGVsbG8=)'
+`), db, "postgres")
+	assert.Nil(t, err)
+
+	var (
+		contents []byte
+		length   int
+	)
+	db.QueryRow("SELECT contents, octet_length(contents) FROM files WHERE id = 1").Scan(&contents, &length)
+	assert.Equal(t, []byte("\x00\x01\xffhello"), contents)
+	assert.Equal(t, 8, length)
+}
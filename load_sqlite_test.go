@@ -1,10 +1,18 @@
 package fixtures
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -352,6 +360,63 @@ func TestLoadFileWorksWithValidFileSQLite(t *testing.T) {
 	assert.Equal(t, 0, count)
 }
 
+func TestLoadFilesNamesTheFailingFileSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The second row of the second file misses a NOT NULL column
+	good := filepath.Join(dir, "good.yml")
+	bad := filepath.Join(dir, "bad.yml")
+	files := map[string]string{
+		good: "- table: 'some_table'\n  pk:\n    id: 1\n  fields:\n    string_field: 'foobar'\n    boolean_field: true\n",
+		bad: "- table: 'other_table'\n  pk:\n    id: 1\n  fields:\n    int_field: 1\n    boolean_field: true\n" +
+			"- table: 'other_table'\n  pk:\n    id: 2\n  fields:\n    boolean_field: true\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = LoadFiles([]string{good, bad}, db, "sqlite")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "Error loading file "+bad+": Error loading row 2 of table other_table")
+	}
+	var processingErr *ProcessingError
+	if assert.True(t, errors.As(err, &processingErr)) {
+		assert.Equal(t, 2, processingErr.Row)
+	}
+
+	// The first file was loaded nonetheless
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
 func TestLoadFileFailssWithMissingFileSQLite(t *testing.T) {
 	// Delete the test database
 	os.Remove(testSQLiteDb)
@@ -379,6 +444,11 @@ func TestLoadFileFailssWithMissingFileSQLite(t *testing.T) {
 
 	// Error should be nil
 	assert.EqualError(t, err, "Error loading file bad_filename.yml: open bad_filename.yml: no such file or directory")
+
+	// The underlying error is kept
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	var pathErr *os.PathError
+	assert.True(t, errors.As(err, &pathErr))
 }
 
 func TestLoadFilesWorksWithValidFilesSQLite(t *testing.T) {
@@ -491,3 +561,2793 @@ func TestLoadFilesFailsWithABadFileSQLite(t *testing.T) {
 	// Error should be nil
 	assert.EqualError(t, err, "Error loading file bad_file: open bad_file: no such file or directory")
 }
+
+func TestLoadWorksWithReservedWordPKSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table whose primary key is a reserved word
+	_, err = db.Exec(`CREATE TABLE reserved_table("order" INT PRIMARY KEY NOT NULL, "group" VARCHAR(50) NOT NULL)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+- table: 'reserved_table'
+  pk:
+    order: 1
+  fields:
+    group: 'foobar'
+`)
+
+	// Load the fixture twice, the second run goes through the WHERE clause
+	err = Load(data, db, "sqlite")
+	assert.Nil(t, err)
+	err = Load(data, db, "sqlite")
+	assert.Nil(t, err)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM reserved_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadContextWorksWithinDeadlineSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Let's load the fixture well within the deadline
+	err = LoadContext(ctx, []byte(testData), db, "sqlite")
+
+	// Error should be nil
+	assert.Nil(t, err)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadContextFailsWhenCancelledSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Loading with a cancelled context should abort without writing anything
+	err = LoadContext(ctx, []byte(testData), db, "sqlite")
+	assert.Equal(t, context.Canceled, err)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 0, count)
+}
+
+func TestLoadDirWorksWithValidDirSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Let's load the whole fixture directory, this should run inserts
+	err = LoadDir(fixtureDir, db, "sqlite")
+
+	// Error should be nil
+	assert.Nil(t, err)
+
+	var count int
+
+	// Check row counts
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM join_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM string_key_table").Scan(&count)
+	assert.Equal(t, 1, count)
+
+	// Let's reload the directory, this should run updates
+	err = LoadDir(fixtureDir, db, "sqlite")
+
+	// Error should be nil
+	assert.Nil(t, err)
+
+	// Check row counts, should be unchanged
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadDirIgnoresNonYAMLFilesSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// An empty directory is a no-op
+	err = LoadDir(dir, db, "sqlite")
+	assert.Nil(t, err)
+
+	// Files other than *.yml and *.yaml must not be loaded
+	files := map[string]string{
+		"1_some.yml":    "- table: 'some_table'\n  pk:\n    id: 1\n  fields:\n    string_field: 'foobar'\n    boolean_field: true\n",
+		"2_other.yaml":  "- table: 'other_table'\n  pk:\n    id: 2\n  fields:\n    int_field: 123\n    boolean_field: false\n",
+		"3_invalid.txt": "this is not a fixture",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = LoadDir(dir, db, "sqlite")
+	assert.Nil(t, err)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadFSWorksWithMatchingFilesSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fsys := fstest.MapFS{
+		"seed/some.yml": &fstest.MapFile{
+			Data: []byte("- table: 'some_table'\n  pk:\n    id: 1\n  fields:\n    string_field: 'foobar'\n    boolean_field: true\n"),
+		},
+		"seed/other.yaml": &fstest.MapFile{
+			Data: []byte("- table: 'other_table'\n  pk:\n    id: 2\n  fields:\n    int_field: 123\n    boolean_field: false\n"),
+		},
+		"seed/README.md": &fstest.MapFile{
+			Data: []byte("not a fixture"),
+		},
+	}
+
+	// Overlapping patterns should load each file only once
+	err = LoadFS(fsys, []string{"seed/*.yml", "seed/*.yaml", "seed/some.*"}, db, "sqlite")
+
+	// Error should be nil
+	assert.Nil(t, err)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+
+	// No match is a no-op
+	err = LoadFS(fsys, []string{"missing/*.yml"}, db, "sqlite")
+	assert.Nil(t, err)
+
+	// A malformed pattern is reported
+	err = LoadFS(fsys, []string{"seed/[.yml"}, db, "sqlite")
+	assert.NotNil(t, err)
+}
+
+func TestLoadTxLeavesTransactionToCallerSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		tx  *sql.Tx
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var count int
+
+	// Load the fixture in a transaction and roll it back
+	tx, err = db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = LoadTx(tx, []byte(testData), "sqlite")
+	assert.Nil(t, err)
+	tx.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	assert.Nil(t, tx.Rollback())
+
+	// Nothing should have been persisted
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 0, count)
+
+	// Load the fixture in a transaction and commit it
+	tx, err = db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = LoadTxContext(context.Background(), tx, []byte(testData), "sqlite")
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+
+	// Now the rows should be persisted
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestTruncateAndLoadEmptiesTablesSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Add rows which are not part of the fixture
+	_, err = db.Exec(`INSERT INTO some_table(id, string_field, boolean_field) VALUES(10, 'stale', 0)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO join_table(some_id, other_id) VALUES(10, 20)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Let's truncate the tables and load the fixture
+	err = TruncateAndLoad([]byte(testData), db, "sqlite")
+
+	// Error should be nil
+	assert.Nil(t, err)
+
+	var count int
+
+	// Only the fixture rows should be left
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM some_table WHERE id = 10").Scan(&count)
+	assert.Equal(t, 0, count)
+	db.QueryRow("SELECT COUNT(*) FROM join_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM join_table WHERE some_id = 10").Scan(&count)
+	assert.Equal(t, 0, count)
+}
+
+func TestLoadDeletesRowsMarkedForDeletionSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Let's load the fixture, since the database is empty, this should run inserts
+	err = Load([]byte(testData), db, "sqlite")
+	assert.Nil(t, err)
+
+	data := []byte(`
+- table: 'join_table'
+  pk:
+    some_id: 1
+    other_id: 2
+  delete: true
+- table: 'some_table'
+  pk:
+    id: 100
+  delete: true
+`)
+
+	// The first row exists and is deleted, the second one is a no-op
+	err = Load(data, db, "sqlite")
+
+	// Error should be nil
+	assert.Nil(t, err)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM join_table").Scan(&count)
+	assert.Equal(t, 0, count)
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadWithUpsertFallsBackSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// SQLite has no upsert statement, loading twice still inserts then updates
+	for i := 0; i < 2; i++ {
+		err = LoadWithContext(context.Background(), []byte(testData), db, "sqlite", &Context{Upsert: true})
+		assert.Nil(t, err)
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM join_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadWithBatchSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foo'
+    boolean_field: true
+- table: 'some_table'
+  pk:
+    id: 2
+  fields:
+    string_field: 'bar'
+    boolean_field: false
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foobar'
+    boolean_field: true
+- table: 'join_table'
+  pk:
+    some_id: 1
+    other_id: 2
+`)
+
+	// Let's load the fixture twice, the first run inserts and the second updates
+	for i := 0; i < 2; i++ {
+		err = LoadWithContext(context.Background(), data, db, "sqlite", &Context{Batch: true})
+		assert.Nil(t, err)
+	}
+
+	var (
+		count       int
+		stringField string
+	)
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 2, count)
+	db.QueryRow("SELECT COUNT(*) FROM join_table").Scan(&count)
+	assert.Equal(t, 1, count)
+
+	// A repeated primary key updates the row inserted earlier in the fixture
+	db.QueryRow("SELECT string_field FROM some_table WHERE id = 1").Scan(&stringField)
+	assert.Equal(t, "foobar", stringField)
+}
+
+func benchmarkLoadSQLite(b *testing.B, c *Context) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	db, err := sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// A fixture with many rows of the same table
+	var data []byte
+	for i := 1; i <= 300; i++ {
+		data = append(data, fmt.Sprintf(
+			"- table: 'some_table'\n  pk:\n    id: %d\n  fields:\n    string_field: 'foobar'\n    boolean_field: true\n",
+			i,
+		)...)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		if _, err := db.Exec("DELETE FROM some_table"); err != nil {
+			log.Fatal(err)
+		}
+		b.StartTimer()
+
+		if err := LoadWithContext(context.Background(), data, db, "sqlite", c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadSQLite(b *testing.B) {
+	benchmarkLoadSQLite(b, &Context{})
+}
+
+func BenchmarkLoadWithBatchSQLite(b *testing.B) {
+	benchmarkLoadSQLite(b, &Context{Batch: true})
+}
+
+func BenchmarkLoadAssumeEmptySQLite(b *testing.B) {
+	benchmarkLoadSQLite(b, &Context{AssumeEmpty: true})
+}
+
+func TestLoadWorksWithSQLite3DriverName(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table with mixed case identifiers
+	_, err = db.Exec(`CREATE TABLE "CamelTable"("Id" INT PRIMARY KEY NOT NULL, "SomeField" VARCHAR(50) NOT NULL)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+- table: 'CamelTable'
+  pk:
+    Id: 1
+  fields:
+    SomeField: 'foobar'
+`)
+
+	// The driver name registered by go-sqlite3 works as well as "sqlite"
+	err = Load(data, db, "sqlite3")
+	assert.Nil(t, err)
+	err = Load(data, db, "sqlite3")
+	assert.Nil(t, err)
+
+	var (
+		count     int
+		someField string
+	)
+	db.QueryRow(`SELECT COUNT(*) FROM "CamelTable"`).Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow(`SELECT "SomeField" FROM "CamelTable" WHERE "Id" = 1`).Scan(&someField)
+	assert.Equal(t, "foobar", someField)
+}
+
+func TestLoadSetsNullFieldsSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var createdAt *time.Time
+
+	// Insert a row with NULL
+	err = Load([]byte(`
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foobar'
+    boolean_field: true
+    created_at: 'NULL()'
+`), db, "sqlite")
+	assert.Nil(t, err)
+	db.QueryRow("SELECT created_at FROM some_table WHERE id = 1").Scan(&createdAt)
+	assert.Nil(t, createdAt)
+
+	// Set the column and then NULL it out again with an update
+	err = Load([]byte(testData), db, "sqlite")
+	assert.Nil(t, err)
+	db.QueryRow("SELECT created_at FROM some_table WHERE id = 1").Scan(&createdAt)
+	assert.Nil(t, createdAt)
+	_, err = db.Exec("UPDATE some_table SET created_at = CURRENT_TIMESTAMP WHERE id = 1")
+	if err != nil {
+		log.Fatal(err)
+	}
+	db.QueryRow("SELECT created_at FROM some_table WHERE id = 1").Scan(&createdAt)
+	assert.NotNil(t, createdAt)
+
+	err = Load([]byte(`
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    created_at: 'NULL()'
+`), db, "sqlite")
+	assert.Nil(t, err)
+	createdAt = nil
+	db.QueryRow("SELECT created_at FROM some_table WHERE id = 1").Scan(&createdAt)
+	assert.Nil(t, createdAt)
+}
+
+func TestLoadWithInsertUUIDSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+- table: 'string_key_table'
+  pk:
+    id: 'ON_INSERT_UUID()'
+  fields:
+    created_at: 'ON_INSERT_NOW()'
+`)
+
+	// Inject a deterministic generator
+	var generated int
+	c := &Context{
+		NewUUID: func() string {
+			generated++
+			return fmt.Sprintf("uuid-%d", generated)
+		},
+	}
+	err = LoadWithContext(context.Background(), data, db, "sqlite", c)
+	assert.Nil(t, err)
+
+	var id string
+	db.QueryRow("SELECT id FROM string_key_table").Scan(&id)
+	assert.Equal(t, "uuid-1", id)
+
+	// Every load generates a new key and so inserts a new row
+	err = Load(data, db, "sqlite")
+	assert.Nil(t, err)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM string_key_table").Scan(&count)
+	assert.Equal(t, 2, count)
+}
+
+func TestLoadWithPinnedClockSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var (
+		insertedAt = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		updatedAt  = time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+		createdAt  *time.Time
+		modifiedAt *time.Time
+	)
+
+	// Insert with the clock pinned to the first instant
+	c := &Context{Now: func() time.Time { return insertedAt }}
+	err = LoadWithContext(context.Background(), []byte(testData), db, "sqlite", c)
+	assert.Nil(t, err)
+
+	// Update with the clock pinned to the second instant
+	c.Now = func() time.Time { return updatedAt }
+	err = LoadWithContext(context.Background(), []byte(testData), db, "sqlite", c)
+	assert.Nil(t, err)
+
+	db.QueryRow("SELECT created_at, updated_at FROM some_table WHERE id = 1").Scan(&createdAt, &modifiedAt)
+	if assert.NotNil(t, createdAt) && assert.NotNil(t, modifiedAt) {
+		assert.True(t, insertedAt.Equal(*createdAt))
+		assert.True(t, updatedAt.Equal(*modifiedAt))
+	}
+}
+
+func TestLoadWorksWithMultipleDocumentsSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The second document updates the row inserted by the first one
+	data := []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foo'
+    boolean_field: true
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'bar'
+- table: 'join_table'
+  pk:
+    some_id: 1
+    other_id: 2
+`)
+
+	err = Load(data, db, "sqlite")
+	assert.Nil(t, err)
+
+	var (
+		count       int
+		stringField string
+	)
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT string_field FROM some_table WHERE id = 1").Scan(&stringField)
+	assert.Equal(t, "bar", stringField)
+	db.QueryRow("SELECT COUNT(*) FROM join_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadJSONWorksWithValidDataSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var (
+		count     int
+		createdAt *time.Time
+		updatedAt *time.Time
+	)
+
+	// Let's load the fixture, since the database is empty, this should run inserts
+	err = LoadJSON([]byte(testDataJSON), db, "sqlite")
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM join_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT created_at, updated_at FROM some_table WHERE id = 1").Scan(&createdAt, &updatedAt)
+	assert.NotNil(t, createdAt)
+	assert.Nil(t, updatedAt)
+
+	// Let's reload the fixture, this should run updates
+	err = LoadJSON([]byte(testDataJSON), db, "sqlite")
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT created_at, updated_at FROM some_table WHERE id = 1").Scan(&createdAt, &updatedAt)
+	assert.NotNil(t, createdAt)
+	assert.NotNil(t, updatedAt)
+}
+
+func TestLoadReportsFailingTableSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The second row misses a NOT NULL column
+	err = Load([]byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foobar'
+    boolean_field: true
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    boolean_field: false
+`), db, "sqlite")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "row 2 of table other_table")
+
+		var processingErr *ProcessingError
+		if assert.True(t, errors.As(err, &processingErr)) {
+			assert.Equal(t, 2, processingErr.Row)
+			assert.Equal(t, "other_table", processingErr.Table)
+			assert.NotNil(t, errors.Unwrap(err))
+		}
+	}
+}
+
+func TestLoadWithContinueOnErrorSkipsFailingRowsSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The second row misses a NOT NULL column
+	data := []byte(`
+---
+- table: 'other_table'
+  pk:
+    id: 1
+  fields:
+    int_field: 1
+    boolean_field: true
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    boolean_field: false
+- table: 'other_table'
+  pk:
+    id: 3
+  fields:
+    int_field: 3
+    boolean_field: true
+`)
+
+	var count int
+
+	// Without ContinueOnError nothing gets loaded
+	err = Load(data, db, "sqlite")
+	assert.NotNil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 0, count)
+
+	// With ContinueOnError only the failing row is skipped
+	err = LoadWithContext(context.Background(), data, db, "sqlite", &Context{ContinueOnError: true, Batch: true})
+	if assert.NotNil(t, err) {
+		var processingErr *ProcessingError
+		if assert.True(t, errors.As(err, &processingErr)) {
+			assert.Equal(t, 2, processingErr.Row)
+			assert.Equal(t, "other_table", processingErr.Table)
+		}
+	}
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 2, count)
+	db.QueryRow("SELECT COUNT(*) FROM other_table WHERE id = 2").Scan(&count)
+	assert.Equal(t, 0, count)
+}
+
+func TestLoadWithMatchOnSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table with a generated primary key and a natural key
+	_, err = db.Exec(`
+CREATE TABLE users(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  email VARCHAR(50) NOT NULL UNIQUE,
+  name VARCHAR(50) NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var (
+		count int
+		name  string
+	)
+
+	// The first load inserts the row
+	err = Load([]byte(`
+---
+- table: 'users'
+  match_on: ['email']
+  fields:
+    email: 'foo@example.com'
+    name: 'Foo'
+`), db, "sqlite")
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	assert.Equal(t, 1, count)
+
+	// The second load finds it by email and updates it
+	err = Load([]byte(`
+---
+- table: 'users'
+  match_on: ['email']
+  fields:
+    email: 'foo@example.com'
+    name: 'Bar'
+`), db, "sqlite")
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT name FROM users WHERE email = 'foo@example.com'").Scan(&name)
+	assert.Equal(t, "Bar", name)
+}
+
+func TestLoadWorksWithSchemaQualifiedTableSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// SQLite names the schema of the main database "main"
+	data := []byte(`
+---
+- table: 'main.other_table'
+  pk:
+    id: 1
+  fields:
+    int_field: 123
+    boolean_field: true
+`)
+
+	var (
+		count    int
+		intField int
+	)
+
+	// Let's load the fixture twice, inserting and then updating the row
+	err = Load(data, db, "sqlite")
+	assert.Nil(t, err)
+	err = Load(data, db, "sqlite")
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT int_field FROM other_table WHERE id = 1").Scan(&intField)
+	assert.Equal(t, 123, intField)
+}
+
+func TestLoadWithDependsOnSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database, foreign keys are enforced
+	// per connection so only one is used
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// Create tables referencing each other
+	_, err = db.Exec(`
+PRAGMA foreign_keys = ON;
+CREATE TABLE posts(
+  id INT PRIMARY KEY NOT NULL,
+  title VARCHAR(50) NOT NULL
+);
+CREATE TABLE comments(
+  id INT PRIMARY KEY NOT NULL,
+  post_id INT NOT NULL REFERENCES posts(id),
+  body VARCHAR(50) NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Comments are listed before the post they reference
+	data := []byte(`
+---
+- table: 'comments'
+  pk:
+    id: 1
+  fields:
+    post_id: 1
+    body: 'First'
+- table: 'posts'
+  pk:
+    id: 1
+  fields:
+    title: 'Hello'
+- table: 'comments'
+  pk:
+    id: 2
+  fields:
+    post_id: 1
+    body: 'Second'
+`)
+
+	var count int
+
+	// Loading the rows in fixture order violates the foreign key
+	err = Load(data, db, "sqlite")
+	assert.NotNil(t, err)
+
+	// Declaring the dependency loads the post first
+	c := &Context{DependsOn: map[string][]string{"comments": {"posts"}}}
+	err = LoadWithContext(context.Background(), data, db, "sqlite", c)
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM comments").Scan(&count)
+	assert.Equal(t, 2, count)
+
+	// Cyclic dependencies are rejected
+	c = &Context{DependsOn: map[string][]string{"comments": {"posts"}, "posts": {"comments"}}}
+	err = LoadWithContext(context.Background(), data, db, "sqlite", c)
+	assert.EqualError(t, err, "Cyclic table dependencies: comments -> posts -> comments")
+}
+
+func TestLoadWithDeferConstraintsSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database, foreign keys are enforced
+	// per connection so only one is used
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// Create tables referencing each other
+	_, err = db.Exec(`
+PRAGMA foreign_keys = ON;
+CREATE TABLE users(
+  id INT PRIMARY KEY NOT NULL,
+  best_friend_id INT NOT NULL REFERENCES users(id)
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Each user references the other
+	data := []byte(`
+---
+- table: 'users'
+  pk:
+    id: 1
+  fields:
+    best_friend_id: 2
+- table: 'users'
+  pk:
+    id: 2
+  fields:
+    best_friend_id: 1
+`)
+
+	var count int
+
+	// No order satisfies immediate checks
+	err = Load(data, db, "sqlite")
+	assert.NotNil(t, err)
+
+	// Deferred checks only run once both rows exist
+	c := &Context{DeferConstraints: true}
+	err = LoadWithContext(context.Background(), data, db, "sqlite", c)
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	assert.Equal(t, 2, count)
+
+	// Rows still violating a foreign key at the end are rejected
+	err = LoadWithContext(context.Background(), []byte(`
+---
+- table: 'users'
+  pk:
+    id: 3
+  fields:
+    best_friend_id: 4
+`), db, "sqlite", c)
+	assert.EqualError(t, err, "FOREIGN KEY constraint failed: row 3 of table users references a missing users row")
+	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	assert.Equal(t, 2, count)
+}
+
+func TestLoadWithResultCountsRowsSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db     *sql.DB
+		err    error
+		result Result
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The empty database gets every row inserted
+	result, err = LoadWithResult(context.Background(), []byte(testData), db, "sqlite", new(Context))
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Inserted: 4}, result)
+
+	// Loading again updates them
+	result, err = LoadWithResult(context.Background(), []byte(testData), db, "sqlite", new(Context))
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Updated: 4}, result)
+
+	// Deleted, inserted and skipped rows are told apart
+	result, err = LoadWithResult(context.Background(), []byte(`
+---
+- table: 'join_table'
+  pk:
+    some_id: 1
+    other_id: 2
+  delete: true
+- table: 'other_table'
+  pk:
+    id: 3
+  fields:
+    int_field: 3
+    boolean_field: true
+- table: 'other_table'
+  pk:
+    id: 4
+  fields:
+    boolean_field: true
+`), db, "sqlite", &Context{ContinueOnError: true})
+	assert.NotNil(t, err)
+	assert.Equal(t, Result{Inserted: 1, Deleted: 1, Skipped: 1}, result)
+}
+
+func TestLoadWithAssumeEmptySQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db     *sql.DB
+		err    error
+		result Result
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c := &Context{AssumeEmpty: true}
+
+	// The empty database gets every row inserted
+	result, err = LoadWithResult(context.Background(), []byte(testData), db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Inserted: 4}, result)
+
+	// Existing rows are not looked for, the duplicate key is reported
+	_, err = LoadWithResult(context.Background(), []byte(testData), db, "sqlite", c)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "Error loading row 1 of table some_table")
+		assert.Contains(t, err.Error(), "UNIQUE constraint failed")
+	}
+
+	// Truncating first makes the load repeatable
+	c.Truncate = true
+	result, err = LoadWithResult(context.Background(), []byte(testData), db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Inserted: 4}, result)
+}
+
+func TestLoadChecksExistenceSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db     *sql.DB
+		err    error
+		result Result
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// One row already exists, the other does not
+	_, err = db.Exec("INSERT INTO other_table(id, int_field, boolean_field) VALUES(1, 0, 0)")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result, err = LoadWithResult(context.Background(), []byte(`
+---
+- table: 'other_table'
+  pk:
+    id: 1
+  fields:
+    int_field: 1
+    boolean_field: true
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    int_field: 2
+    boolean_field: true
+`), db, "sqlite", new(Context))
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Inserted: 1, Updated: 1}, result)
+
+	var intField int
+	db.QueryRow("SELECT int_field FROM other_table WHERE id = 1").Scan(&intField)
+	assert.Equal(t, 1, intField)
+	db.QueryRow("SELECT int_field FROM other_table WHERE id = 2").Scan(&intField)
+	assert.Equal(t, 2, intField)
+}
+
+func TestLoadCallsRowHooksSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var calls []string
+	c := &Context{
+		BeforeRow: func(row *Row) {
+			calls = append(calls, "before "+row.Table)
+		},
+		AfterRow: func(row *Row, err error) {
+			calls = append(calls, fmt.Sprintf("after %s: %v", row.Table, err))
+		},
+	}
+
+	// The hooks are called around every row
+	err = LoadWithContext(context.Background(), []byte(testData), db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"before some_table", "after some_table: <nil>",
+		"before other_table", "after other_table: <nil>",
+		"before join_table", "after join_table: <nil>",
+		"before string_key_table", "after string_key_table: <nil>",
+	}, calls)
+
+	// A failing row is passed its error
+	calls = nil
+	err = LoadWithContext(context.Background(), []byte(`
+---
+- table: 'other_table'
+  pk:
+    id: 3
+  fields:
+    boolean_field: true
+`), db, "sqlite", c)
+	assert.NotNil(t, err)
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, "after other_table: "+err.Error(), calls[1])
+	}
+}
+
+func TestLoadWithVarsSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 'VAR(id)'
+  fields:
+    string_field: 'VAR(tenant)'
+    boolean_field: true
+`)
+
+	var (
+		count       int
+		stringField string
+	)
+
+	// Variables are taken from the context
+	c := &Context{Vars: map[string]interface{}{"id": 5, "tenant": "acme"}}
+	err = LoadWithContext(context.Background(), data, db, "sqlite", c)
+	assert.Nil(t, err)
+	db.QueryRow("SELECT string_field FROM some_table WHERE id = 5").Scan(&stringField)
+	assert.Equal(t, "acme", stringField)
+
+	// A missing variable fails the load before any query
+	c = &Context{Vars: map[string]interface{}{"id": 6}}
+	err = LoadWithContext(context.Background(), data, db, "sqlite", c)
+	assert.EqualError(t, err, "Error loading row 1 of table some_table: unknown variable tenant used by column string_field")
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadWithExprSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: "EXPR('foo' || 'bar')"
+    boolean_field: true
+- table: 'some_table'
+  pk:
+    id: 2
+  fields:
+    string_field: 'baz'
+    boolean_field: "EXPR(1 = 1)"
+`)
+
+	var (
+		stringField  string
+		booleanField bool
+	)
+
+	// Let's load the fixture twice, inserting and then updating the rows
+	for i := 0; i < 2; i++ {
+		err = LoadWithContext(context.Background(), data, db, "sqlite", &Context{Batch: true})
+		assert.Nil(t, err)
+		db.QueryRow("SELECT string_field FROM some_table WHERE id = 1").Scan(&stringField)
+		assert.Equal(t, "foobar", stringField)
+		db.QueryRow("SELECT string_field, boolean_field FROM some_table WHERE id = 2").Scan(&stringField, &booleanField)
+		assert.Equal(t, "baz", stringField)
+		assert.True(t, booleanField)
+	}
+}
+
+func TestLoadRowWorksWithRowsBuiltInGoSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	row := Row{
+		Table: "some_table",
+		PK:    map[string]interface{}{"id": 1},
+		Fields: map[string]interface{}{
+			"string_field":  "foobar",
+			"boolean_field": true,
+			"created_at":    "ON_INSERT_NOW()",
+		},
+	}
+
+	var (
+		count       int
+		stringField string
+		createdAt   *time.Time
+	)
+
+	// Markers work the same as in fixtures
+	err = LoadRow(context.Background(), row, db, "sqlite", new(Context))
+	assert.Nil(t, err)
+	db.QueryRow("SELECT string_field, created_at FROM some_table WHERE id = 1").Scan(&stringField, &createdAt)
+	assert.Equal(t, "foobar", stringField)
+	assert.NotNil(t, createdAt)
+
+	// Existing rows are updated
+	row.Fields["string_field"] = "baz"
+	err = LoadRows(context.Background(), []Row{row, {
+		Table:  "other_table",
+		PK:     map[string]interface{}{"id": 2},
+		Fields: map[string]interface{}{"int_field": 123, "boolean_field": false},
+	}}, db, "sqlite", new(Context))
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT string_field FROM some_table WHERE id = 1").Scan(&stringField)
+	assert.Equal(t, "baz", stringField)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestParseRowsThenLoadRowsSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rows, err := ParseRows([]byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foo'
+    boolean_field: true
+---
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    int_field: 123
+    boolean_field: false
+`))
+	assert.Nil(t, err)
+	if !assert.Len(t, rows, 2) {
+		return
+	}
+	assert.Equal(t, "some_table", rows[0].Table)
+	assert.Equal(t, "foo", rows[0].Fields["string_field"])
+	assert.Equal(t, "other_table", rows[1].Table)
+
+	// Rows changed between parsing and loading are loaded as changed
+	rows[0].Fields["string_field"] = "bar"
+	err = LoadRows(context.Background(), rows, db, "sqlite", new(Context))
+	assert.Nil(t, err)
+
+	var (
+		count       int
+		stringField string
+	)
+	db.QueryRow("SELECT string_field FROM some_table WHERE id = 1").Scan(&stringField)
+	assert.Equal(t, "bar", stringField)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+
+	// Malformed YAML is reported without loading anything
+	_, err = ParseRows([]byte(`- table: [`))
+	assert.NotNil(t, err)
+}
+
+func TestLoadNoTxKeepsRowsBeforeFailureSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The second row misses a NOT NULL column
+	data := []byte(`
+---
+- table: 'other_table'
+  pk:
+    id: 1
+  fields:
+    int_field: 1
+    boolean_field: true
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    boolean_field: false
+- table: 'other_table'
+  pk:
+    id: 3
+  fields:
+    int_field: 3
+    boolean_field: true
+`)
+
+	var count int
+
+	// Rows loaded before the failing one stay committed
+	err = LoadNoTx(data, db, "sqlite")
+	if assert.NotNil(t, err) {
+		var processingErr *ProcessingError
+		if assert.True(t, errors.As(err, &processingErr)) {
+			assert.Equal(t, 2, processingErr.Row)
+		}
+	}
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM other_table WHERE id = 1").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadWithWhenGuardSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The second row is only seeded in development
+	data := []byte(`
+---
+- table: 'other_table'
+  pk:
+    id: 1
+  fields:
+    int_field: 1
+    boolean_field: true
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    int_field: 2
+    boolean_field: false
+  when:
+    env: 'dev'
+`)
+
+	var count int
+
+	// Unmatched guards skip the row
+	c := &Context{Vars: map[string]interface{}{"env": "prod"}}
+	err = LoadWithContext(context.Background(), data, db, "sqlite", c)
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+
+	// Matched guards load the row
+	c = &Context{Vars: map[string]interface{}{"env": "dev"}}
+	err = LoadWithContext(context.Background(), data, db, "sqlite", c)
+	assert.Nil(t, err)
+	db.QueryRow("SELECT COUNT(*) FROM other_table WHERE id = 2").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadSkipUnchangedSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema logging every update
+	_, err = db.Exec(testSchemaSQLite + `
+CREATE TABLE updates(id INT NOT NULL);
+CREATE TRIGGER log_updates AFTER UPDATE ON some_table BEGIN
+  INSERT INTO updates(id) VALUES(new.id);
+END;
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := `
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: '%s'
+    boolean_field: true
+    created_at: 'NULL()'
+    updated_at: 'ON_UPDATE_NOW()'
+`
+
+	var (
+		result Result
+		count  int
+	)
+	c := &Context{SkipUnchanged: true}
+
+	result, err = LoadWithResult(context.Background(), []byte(fmt.Sprintf(data, "foo")), db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Inserted: 1}, result)
+
+	// Loading the same values again fires no UPDATE
+	result, err = LoadWithResult(context.Background(), []byte(fmt.Sprintf(data, "foo")), db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Unchanged: 1}, result)
+	db.QueryRow("SELECT COUNT(*) FROM updates").Scan(&count)
+	assert.Equal(t, 0, count)
+
+	// A changed value is updated
+	result, err = LoadWithResult(context.Background(), []byte(fmt.Sprintf(data, "bar")), db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Updated: 1}, result)
+	db.QueryRow("SELECT COUNT(*) FROM updates").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadSkipUnchangedKeepsUpdatedAtSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := `
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: '%s'
+    boolean_field: true
+    updated_at: 'ON_UPDATE_NOW()'
+`
+	now := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	c := &Context{SkipUnchanged: true, Now: func() time.Time { return now }}
+
+	var updatedAt *time.Time
+
+	// updated_at is only set once the row is updated
+	err = LoadWithContext(context.Background(), []byte(fmt.Sprintf(data, "foo")), db, "sqlite", c)
+	assert.Nil(t, err)
+	err = LoadWithContext(context.Background(), []byte(fmt.Sprintf(data, "bar")), db, "sqlite", c)
+	assert.Nil(t, err)
+	db.QueryRow("SELECT updated_at FROM some_table WHERE id = 1").Scan(&updatedAt)
+	if assert.NotNil(t, updatedAt) {
+		assert.True(t, now.Equal(*updatedAt))
+	}
+
+	// Reseeding the same values later leaves updated_at alone, as
+	// ON_UPDATE_NOW() columns are not compared
+	now = now.Add(time.Hour)
+	result, err := LoadWithResult(context.Background(), []byte(fmt.Sprintf(data, "bar")), db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Unchanged: 1}, result)
+	db.QueryRow("SELECT updated_at FROM some_table WHERE id = 1").Scan(&updatedAt)
+	if assert.NotNil(t, updatedAt) {
+		assert.True(t, now.Add(-time.Hour).Equal(*updatedAt))
+	}
+
+	// A changed column bumps it
+	result, err = LoadWithResult(context.Background(), []byte(fmt.Sprintf(data, "baz")), db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Updated: 1}, result)
+	db.QueryRow("SELECT updated_at FROM some_table WHERE id = 1").Scan(&updatedAt)
+	if assert.NotNil(t, updatedAt) {
+		assert.True(t, now.Equal(*updatedAt))
+	}
+}
+
+func BenchmarkLoadSkipUnchangedSQLite(b *testing.B) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	// Connect to an in-memory SQLite database
+	db, err := sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// A fixture of rows which are already loaded
+	var data []byte
+	for i := 1; i <= 300; i++ {
+		data = append(data, fmt.Sprintf(
+			"- table: 'some_table'\n  pk:\n    id: %d\n  fields:\n    string_field: 'foobar'\n    boolean_field: true\n",
+			i,
+		)...)
+	}
+	if err := Load(data, db, "sqlite"); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name    string
+		context Context
+	}{
+		{"Update", Context{}},
+		{"SkipUnchanged", Context{SkipUnchanged: true}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				options := c.context
+				if err := LoadWithContext(context.Background(), data, db, "sqlite", &options); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadErrorHoldsFailedQuerySQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The row misses a NOT NULL column so its insert fails
+	err = Load([]byte(`
+---
+- table: 'other_table'
+  pk:
+    id: 1
+  fields:
+    boolean_field: true
+`), db, "sqlite")
+	var processingErr *ProcessingError
+	if assert.True(t, errors.As(err, &processingErr)) {
+		assert.Equal(t, `INSERT INTO "other_table"("id", "boolean_field") VALUES(?, ?)`, processingErr.Query)
+		assert.Equal(t, []interface{}{1, true}, processingErr.Args)
+	}
+
+	// So does a failing batch
+	err = LoadWithContext(context.Background(), []byte(`
+---
+- table: 'other_table'
+  pk:
+    id: 1
+  fields:
+    boolean_field: true
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    boolean_field: false
+`), db, "sqlite", &Context{Batch: true})
+	if assert.True(t, errors.As(err, &processingErr)) {
+		assert.Equal(t, `INSERT INTO "other_table"("id", "boolean_field") VALUES(?, ?), (?, ?)`, processingErr.Query)
+		assert.Equal(t, []interface{}{1, true, 2, false}, processingErr.Args)
+	}
+}
+
+func TestLoadWithTableModeSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Load rows of which some are then loaded again
+	err = Load([]byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foo'
+    boolean_field: true
+- table: 'other_table'
+  pk:
+    id: 1
+  fields:
+    int_field: 1
+    boolean_field: true
+`), db, "sqlite")
+	assert.Nil(t, err)
+
+	data := []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'bar'
+    boolean_field: true
+- table: 'some_table'
+  pk:
+    id: 2
+  fields:
+    string_field: 'bar'
+    boolean_field: true
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    int_field: 2
+    boolean_field: true
+- table: 'string_key_table'
+  pk:
+    id: 'foo'
+`)
+	c := &Context{TableMode: map[string]Mode{
+		"some_table":       ModeUpdateOnly,
+		"other_table":      ModeInsertOnly,
+		"string_key_table": ModeUpsert,
+	}}
+	result, err := LoadWithResult(context.Background(), data, db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Inserted: 2, Updated: 1}, result)
+
+	// The existing row is updated and the missing one left out
+	var (
+		stringField string
+		count       int
+	)
+	db.QueryRow("SELECT string_field FROM some_table WHERE id = 1").Scan(&stringField)
+	assert.Equal(t, "bar", stringField)
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 2, count)
+
+	// SQLite has no upserts so string_key_table falls back to the default
+	db.QueryRow("SELECT COUNT(*) FROM string_key_table").Scan(&count)
+	assert.Equal(t, 1, count)
+
+	// Rows always inserted fail once they exist
+	_, err = LoadWithResult(context.Background(), data, db, "sqlite", c)
+	assert.NotNil(t, err)
+}
+
+func TestLoadWithValueTransformerSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Uppercase the string field of some_table only
+	c := &Context{ValueTransformer: func(table, column string, value interface{}) (interface{}, error) {
+		if table == "some_table" && column == "string_field" {
+			return strings.ToUpper(value.(string)), nil
+		}
+		return value, nil
+	}}
+	err = LoadWithContext(context.Background(), []byte(testData), db, "sqlite", c)
+	assert.Nil(t, err)
+
+	var stringField string
+	db.QueryRow("SELECT string_field FROM some_table WHERE id = 1").Scan(&stringField)
+	assert.Equal(t, "FOOBAR", stringField)
+
+	// An error rolls the load back
+	c = &Context{ValueTransformer: func(table, column string, value interface{}) (interface{}, error) {
+		if table == "other_table" {
+			return nil, errors.New("cannot transform")
+		}
+		return value, nil
+	}}
+	err = LoadWithContext(context.Background(), []byte(testData), db, "sqlite", c)
+	var processingErr *ProcessingError
+	if assert.True(t, errors.As(err, &processingErr)) {
+		assert.Equal(t, "other_table", processingErr.Table)
+	}
+
+	// So some_table keeps its uppercased value
+	db.QueryRow("SELECT string_field FROM some_table WHERE id = 1").Scan(&stringField)
+	assert.Equal(t, "FOOBAR", stringField)
+}
+
+func TestLoadSkipsCommentRowsSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+---
+- table: '#'
+  fields:
+    note: 'Seed data for the dashboard tests'
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foobar'
+    boolean_field: true
+- table: '#'
+  pk:
+    id: 2
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    int_field: 123
+    boolean_field: false
+`)
+	assert.Nil(t, Validate(data))
+
+	result, err := LoadWithResult(context.Background(), data, db, "sqlite", &Context{Truncate: true})
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Inserted: 2}, result)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadReportsProgressSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Progress adds up over the documents, skipping the empty and comment
+	// rows while counting the guarded one
+	var calls [][2]int
+	c := &Context{Vars: map[string]interface{}{"env": "test"}, Progress: func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	}}
+	err = LoadWithContext(context.Background(), []byte(`
+---
+- table: '#'
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foobar'
+    boolean_field: true
+-
+---
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    int_field: 123
+    boolean_field: false
+- table: 'join_table'
+  pk:
+    some_id: 1
+    other_id: 2
+  when:
+    env: 'dev'
+`), db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, [][2]int{{1, 3}, {2, 3}, {3, 3}}, calls)
+}
+
+func TestLoadWithBatchOverMaxArgsSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 500 rows of 3 values bind more than the 999 values SQLite allows
+	var data []byte
+	for i := 1; i <= 500; i++ {
+		data = append(data, fmt.Sprintf(
+			"- table: 'some_table'\n  pk:\n    id: %d\n  fields:\n    string_field: 'foobar'\n    boolean_field: true\n",
+			i,
+		)...)
+	}
+	var count int
+	for _, c := range []*Context{
+		{Batch: true},
+		{Batch: true, Truncate: true, MaxArgs: 10},
+	} {
+		err = LoadWithContext(context.Background(), data, db, "sqlite", c)
+		assert.Nil(t, err)
+		db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+		assert.Equal(t, 500, count)
+	}
+}
+
+func TestCleanEmptiesFixtureTablesSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Seed every table
+	err = Load([]byte(testData), db, "sqlite")
+	assert.Nil(t, err)
+
+	// Only the tables of the fixture are emptied
+	err = Clean([]byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+- table: 'other_table'
+  pk:
+    id: 1
+`), db, "sqlite")
+	assert.Nil(t, err)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 0, count)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 0, count)
+	db.QueryRow("SELECT COUNT(*) FROM join_table").Scan(&count)
+	assert.Equal(t, 1, count)
+	db.QueryRow("SELECT COUNT(*) FROM string_key_table").Scan(&count)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoadWithUpdateColumnsSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data := []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    string_field: 'foobar'
+    boolean_field: true
+  update_columns: ['boolean_field']
+`)
+	err = Load(data, db, "sqlite")
+	assert.Nil(t, err)
+
+	// Rerunning only patches the listed column
+	_, err = db.Exec("UPDATE some_table SET string_field = 'changed', boolean_field = 0")
+	assert.Nil(t, err)
+	err = Load(data, db, "sqlite")
+	assert.Nil(t, err)
+
+	var (
+		stringField  string
+		booleanField bool
+	)
+	db.QueryRow("SELECT string_field, boolean_field FROM some_table WHERE id = 1").Scan(&stringField, &booleanField)
+	assert.Equal(t, "changed", stringField)
+	assert.True(t, booleanField)
+}
+
+func TestLoadLogsRowsLeftUntouchedSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The key of the row is never updated and its only field is written on
+	// insert, so an existing row has nothing to update
+	data := []byte(`
+---
+- table: 'string_key_table'
+  pk:
+    id: 'foo'
+  fields:
+    created_at: 'ON_INSERT_NOW()'
+  override_system_value: true
+`)
+	var logged bytes.Buffer
+	c := &Context{Logger: log.New(&logged, "", 0)}
+
+	result, err := LoadWithResult(context.Background(), data, db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Inserted: 1}, result)
+	assert.Empty(t, logged.String())
+
+	// Loading it again is a no-op, which is logged rather than counted
+	result, err = LoadWithResult(context.Background(), data, db, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{}, result)
+	assert.Equal(t, "Row 1 of table string_key_table already exists and has no column to update, leaving it untouched\n", logged.String())
+}
+
+func TestLoadWithColumnMapSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// camelCase keys are mapped to the snake_case columns, id is unmapped
+	data := []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    stringField: 'foo'
+    booleanField: true
+    createdAt: 'ON_INSERT_NOW()'
+`)
+	c := &Context{ColumnMap: map[string]string{
+		"stringField":  "string_field",
+		"booleanField": "boolean_field",
+		"createdAt":    "created_at",
+	}}
+	err = LoadWithContext(context.Background(), data, db, "sqlite", c)
+	assert.Nil(t, err)
+
+	var (
+		stringField  string
+		booleanField bool
+		createdAt    *time.Time
+	)
+	err = db.QueryRow("SELECT string_field, boolean_field, created_at FROM some_table WHERE id = 1").Scan(&stringField, &booleanField, &createdAt)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", stringField)
+	assert.True(t, booleanField)
+	assert.NotNil(t, createdAt)
+
+	// Without the map the keys are taken for columns
+	err = LoadWithContext(context.Background(), data, db, "sqlite", new(Context))
+	assert.NotNil(t, err)
+}
+
+func TestLoadWithRollbackSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a test schema
+	_, err = db.Exec(testSchemaSQLite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The rows are loaded and counted but do not persist
+	result, err := LoadWithResult(context.Background(), []byte(testData), db, "sqlite", &Context{Rollback: true})
+	assert.Nil(t, err)
+	assert.NotZero(t, result.Inserted)
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+	assert.Equal(t, 0, count)
+	db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+	assert.Equal(t, 0, count)
+
+	// Failing rows are still reported
+	err = LoadWithContext(context.Background(), []byte(`
+---
+- table: 'missing_table'
+  pk:
+    id: 1
+`), db, "sqlite", &Context{Rollback: true})
+	assert.NotNil(t, err)
+}
+
+func TestLoadBytesIntoBlobSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table with a BLOB column
+	_, err = db.Exec(`
+CREATE TABLE files(
+  id INTEGER PRIMARY KEY NOT NULL,
+  contents BLOB NOT NULL
+);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = Load([]byte(`
+---
+- table: 'files'
+  pk:
+    id: 1
+  fields:
+    contents: 'BYTES(AAH/aGVsbG8=)'
+`), db, "sqlite")
+	assert.Nil(t, err)
+
+	var (
+		contents []byte
+		typ      string
+	)
+	db.QueryRow("SELECT contents, typeof(contents) FROM files WHERE id = 1").Scan(&contents, &typ)
+	assert.Equal(t, []byte("\x00\x01\xffhello"), contents)
+	assert.Equal(t, "blob", typ)
+
+	// Invalid base64 fails before any query, naming the row
+	err = Load([]byte(`
+---
+- table: 'files'
+  pk:
+    id: 2
+  fields:
+    contents: 'BYTES(not base64)'
+`), db, "sqlite")
+	assert.EqualError(t, err, "Error loading row 1 of table files: column contents of table files: malformed marker BYTES(not base64), expected BYTES(base64)")
+}
+
+func TestLoadTargetsSQLite(t *testing.T) {
+	// Connect to two in-memory SQLite databases, kept on a single connection
+	// each as every connection gets its own in-memory database
+	dbs := make([]*sql.DB, 2)
+	for i := range dbs {
+		db, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		db.SetMaxOpenConns(1)
+
+		// Create a test schema
+		if _, err := db.Exec(testSchemaSQLite); err != nil {
+			log.Fatal(err)
+		}
+		dbs[i] = db
+	}
+	targets := []Target{{DB: dbs[0], Driver: "sqlite"}, {DB: dbs[1], Driver: "sqlite3"}}
+
+	// The fixture is loaded into both databases
+	err := LoadTargets(context.Background(), []byte(testData), targets, new(Context))
+	assert.Nil(t, err)
+	var count int
+	for _, db := range dbs {
+		db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+		assert.Equal(t, 1, count)
+		db.QueryRow("SELECT COUNT(*) FROM other_table").Scan(&count)
+		assert.Equal(t, 1, count)
+	}
+
+	// A failing target rolls back the others
+	_, err = dbs[1].Exec("DROP TABLE other_table")
+	assert.Nil(t, err)
+	err = LoadTargets(context.Background(), []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 2
+  fields:
+    string_field: 'foo'
+    boolean_field: true
+- table: 'other_table'
+  pk:
+    id: 3
+  fields:
+    int_field: 1
+    boolean_field: false
+`), targets, new(Context))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "Error loading target 2 (sqlite3)")
+	}
+	for _, db := range dbs {
+		db.QueryRow("SELECT COUNT(*) FROM some_table").Scan(&count)
+		assert.Equal(t, 1, count)
+	}
+}
+
+func TestLoadRowsWithTableTemplateSQLite(t *testing.T) {
+	// Delete the test database
+	os.Remove(testSQLiteDb)
+
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	// Connect to an in-memory SQLite database
+	db, err = sql.Open("sqlite3", testSQLiteDb)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Create a table per tenant
+	_, err = db.Exec(`
+CREATE TABLE users_t1(id INTEGER PRIMARY KEY NOT NULL, name TEXT NOT NULL);
+CREATE TABLE users_t2(id INTEGER PRIMARY KEY NOT NULL, name TEXT NOT NULL);
+`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rows, err := ParseRows([]byte(`
+---
+- table: 'users_{tenant}'
+  pk:
+    id: 1
+  fields:
+    name: 'foo'
+`))
+	assert.Nil(t, err)
+
+	// The same rows are loaded into the table of every tenant
+	for _, tenant := range []string{"t1", "t2"} {
+		c := &Context{Truncate: true, Vars: map[string]interface{}{"tenant": tenant}}
+		err = LoadRows(context.Background(), rows, db, "sqlite", c)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, "users_{tenant}", rows[0].Table)
+
+	var count int
+	for _, table := range []string{"users_t1", "users_t2"} {
+		db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count)
+		assert.Equal(t, 1, count, table)
+	}
+}
@@ -1,5 +1,21 @@
 package fixtures
 
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
 var testSchemaSQLite = `
 CREATE TABLE some_table(
   id INT PRIMARY KEY NOT NULL,
@@ -91,9 +107,848 @@ var testData = `
 `
 
 var (
+	fixtureDir   = "fixtures"
 	fixtureFile  = "fixtures/test_fixtures1.yml"
 	fixtureFiles = []string{
 		"fixtures/test_fixtures1.yml",
 		"fixtures/test_fixtures2.yml",
 	}
 )
+
+func TestRender(t *testing.T) {
+	data := []byte(`
+- table: 'join_table'
+  pk:
+    some_id: 1
+    other_id: 2
+- table: 'string_key_table'
+  pk:
+    id: 'old_id'
+  delete: true
+`)
+
+	// Every row gets its SELECT, INSERT and UPDATE, deleted rows a DELETE
+	statements, err := Render(data, "postgres")
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		{
+			Query: `SELECT EXISTS(SELECT 1 FROM "join_table" WHERE "other_id" = $1 AND "some_id" = $2)`,
+			Args:  []interface{}{2, 1},
+		},
+		{
+			Query: `INSERT INTO "join_table"("other_id", "some_id") VALUES($1, $2)`,
+			Args:  []interface{}{2, 1},
+		},
+		{
+			Query: `UPDATE "join_table" SET "other_id" = $1, "some_id" = $2 WHERE "other_id" = $3 AND "some_id" = $4`,
+			Args:  []interface{}{2, 1, 2, 1},
+		},
+		{
+			Query: `DELETE FROM "string_key_table" WHERE "id" = $1`,
+			Args:  []interface{}{"old_id"},
+		},
+	}, statements)
+
+	// Options are honoured in dry run mode
+	c := &Context{DryRun: true, Truncate: true, Upsert: true}
+	err = LoadWithContext(context.Background(), data, nil, "mysql", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		{Query: "DELETE FROM `string_key_table`"},
+		{Query: "DELETE FROM `join_table`"},
+		{
+			Query: "INSERT INTO `join_table`(`other_id`, `some_id`) VALUES(?, ?) " +
+				"ON DUPLICATE KEY UPDATE `other_id` = ?, `some_id` = ?",
+			Args: []interface{}{2, 1, 2, 1},
+		},
+		{
+			Query: "DELETE FROM `string_key_table` WHERE `id` = ?",
+			Args:  []interface{}{"old_id"},
+		},
+	}, c.Statements)
+
+	// Invalid YAML is reported
+	_, err = Render([]byte("{"), "postgres")
+	assert.NotNil(t, err)
+}
+
+func TestParseFixtureWithMultipleDocuments(t *testing.T) {
+	data := []byte(`# leading comment
+---
+- table: 'some_table'
+  pk:
+    id: 1
+--- # second document
+- table: 'other_table'
+  pk:
+    id: 2
+- table: 'join_table'
+  pk:
+    some_id: 1
+    other_id: 2
+...
+--- [{table: 'string_key_table', pk: {id: 'new_id'}}]
+`)
+
+	rows, err := parseFixture(data)
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 4) {
+		assert.Equal(t, "some_table", rows[0].Table)
+		assert.Equal(t, "other_table", rows[1].Table)
+		assert.Equal(t, "join_table", rows[2].Table)
+		assert.Equal(t, "string_key_table", rows[3].Table)
+	}
+
+	// A single document without markers still works
+	rows, err = parseFixture([]byte(testData))
+	assert.Nil(t, err)
+	assert.Len(t, rows, 4)
+
+	// Directives stay with the document they come before
+	rows, err = parseFixture([]byte("%YAML 1.1\n---\n- table: 'some_table'\n  pk:\n    id: 1\n...\n%YAML 1.1\n--- [{table: 'other_table', pk: {id: 2}}]\n"))
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, "some_table", rows[0].Table)
+		assert.Equal(t, "other_table", rows[1].Table)
+	}
+
+	// An invalid document is reported
+	_, err = parseFixture([]byte("- table: 'some_table'\n---\n{"))
+	assert.NotNil(t, err)
+}
+
+var testDataJSON = `
+[
+  {
+    "table": "some_table",
+    "pk": {"id": 1},
+    "fields": {
+      "string_field": "foobar",
+      "boolean_field": true,
+      "created_at": "ON_INSERT_NOW()",
+      "updated_at": "ON_UPDATE_NOW()"
+    }
+  },
+  {
+    "table": "other_table",
+    "pk": {"id": 2},
+    "fields": {
+      "int_field": 123,
+      "boolean_field": false
+    }
+  },
+  {
+    "table": "join_table",
+    "pk": {"some_id": 1, "other_id": 2}
+  }
+]
+`
+
+func TestParseJSONFixture(t *testing.T) {
+	rows, err := parseJSONFixture([]byte(testDataJSON))
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 3) {
+		assert.Equal(t, "some_table", rows[0].Table)
+		assert.Equal(t, map[string]interface{}{"id": int64(1)}, rows[0].PK)
+		assert.Equal(t, "ON_INSERT_NOW()", rows[0].Fields["created_at"])
+		assert.Equal(t, int64(123), rows[1].Fields["int_field"])
+		assert.Equal(t, map[string]interface{}{"some_id": int64(1), "other_id": int64(2)}, rows[2].PK)
+	}
+
+	// Non integral numbers are kept as floats
+	rows, err = parseJSONFixture([]byte(`[{"table": "t", "pk": {"id": 1}, "fields": {"f": 1.5}}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, 1.5, rows[0].Fields["f"])
+
+	// Invalid JSON is reported
+	_, err = parseJSONFixture([]byte(`[{"table": }]`))
+	assert.NotNil(t, err)
+}
+
+func TestProcessingError(t *testing.T) {
+	cause := errors.New("constraint failed")
+
+	var err error = &ProcessingError{Row: 3, Table: "some_table", Column: "id", Err: cause}
+	assert.Equal(t, "Error loading row 3 of table some_table (column id): constraint failed", err.Error())
+	assert.True(t, errors.Is(err, cause))
+
+	// Table and column are left out when unknown
+	err = NewProcessingError(3, cause)
+	assert.Equal(t, "Error loading row 3: constraint failed", err.Error())
+	assert.Equal(t, cause, errors.Unwrap(err))
+	assert.True(t, errors.Is(err, cause))
+}
+
+func TestFileError(t *testing.T) {
+	err := NewFileError("fixtures.yml", sql.ErrNoRows)
+	assert.Equal(t, "Error loading file fixtures.yml: sql: no rows in result set", err.Error())
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
+
+func TestRenderRejectsOverlappingColumns(t *testing.T) {
+	_, err := Render([]byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+- table: 'other_table'
+  pk:
+    id: 2
+  fields:
+    id: 3
+    int_field: 123
+`), "postgres")
+	assert.EqualError(t, err, "Error loading row 2 of table other_table: column id of table other_table is set in both pk and fields")
+}
+
+func TestDryRunWithCustomQuote(t *testing.T) {
+	c := &Context{
+		DryRun:   true,
+		Truncate: true,
+		Quote: func(identifier string) string {
+			return "[" + identifier + "]"
+		},
+	}
+	err := LoadWithContext(context.Background(), []byte(`
+---
+- table: 'billing.invoices'
+  pk:
+    id: 1
+  fields:
+    amount: 100
+`), nil, "sqlite", c)
+	assert.Nil(t, err)
+
+	queries := make([]string, len(c.Statements))
+	for i, statement := range c.Statements {
+		queries[i] = statement.Query
+	}
+	assert.Equal(t, []string{
+		"DELETE FROM [billing].[invoices]",
+		"SELECT EXISTS(SELECT 1 FROM [billing].[invoices] WHERE [id] = ?)",
+		"INSERT INTO [billing].[invoices]([id], [amount]) VALUES(?, ?)",
+		"UPDATE [billing].[invoices] SET [id] = ?, [amount] = ? WHERE [id] = ?",
+	}, queries)
+}
+
+func TestDryRunWithUnquotedIdentifiers(t *testing.T) {
+	data := []byte(`
+---
+- table: 'billing.Invoices'
+  pk:
+    Id: 1
+  fields:
+    Amount: 100
+`)
+
+	// queries returns the queries rendered for the fixture with c
+	queries := func(c *Context) []string {
+		c.DryRun = true
+		c.Truncate = true
+		err := LoadWithContext(context.Background(), data, nil, "postgres", c)
+		assert.Nil(t, err)
+		queries := make([]string, len(c.Statements))
+		for i, statement := range c.Statements {
+			queries[i] = statement.Query
+		}
+		return queries
+	}
+
+	// Identifiers are quoted by default, keeping their case
+	assert.Equal(t, []string{
+		`TRUNCATE TABLE "billing"."Invoices" RESTART IDENTITY CASCADE`,
+		`SELECT EXISTS(SELECT 1 FROM "billing"."Invoices" WHERE "Id" = $1)`,
+		`INSERT INTO "billing"."Invoices"("Id", "Amount") VALUES($1, $2)`,
+		`UPDATE "billing"."Invoices" SET "Id" = $1, "Amount" = $2 WHERE "Id" = $3`,
+	}, queries(new(Context)))
+
+	// Unquoted identifiers are written as spelled, Postgres folds them
+	assert.Equal(t, []string{
+		`TRUNCATE TABLE billing.Invoices RESTART IDENTITY CASCADE`,
+		`SELECT EXISTS(SELECT 1 FROM billing.Invoices WHERE Id = $1)`,
+		`INSERT INTO billing.Invoices(Id, Amount) VALUES($1, $2)`,
+		`UPDATE billing.Invoices SET Id = $1, Amount = $2 WHERE Id = $3`,
+	}, queries(&Context{UnquotedIdentifiers: true}))
+}
+
+func TestValidate(t *testing.T) {
+	// A valid fixture, variables are only checked when loading
+	err := Validate([]byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    tenant_id: 'VAR(tenant)'
+`))
+	assert.Nil(t, err)
+
+	// Malformed YAML
+	err = Validate([]byte(`
+- table: 'some_table'
+  pk: [
+`))
+	assert.NotNil(t, err)
+
+	// Missing table
+	err = Validate([]byte(`
+- table: 'some_table'
+  pk:
+    id: 1
+- pk:
+    id: 2
+`))
+	assert.EqualError(t, err, "Error loading row 2: table is not set")
+
+	// Column in both pk and fields
+	err = Validate([]byte(`
+- table: 'some_table'
+  pk:
+    id: 1
+  fields:
+    id: 2
+`))
+	assert.EqualError(t, err, "Error loading row 1 of table some_table: column id of table some_table is set in both pk and fields")
+
+	// Unset match_on column
+	err = Validate([]byte(`
+- table: 'users'
+  match_on: ['email']
+  fields:
+    name: 'Foo'
+`))
+	assert.EqualError(t, err, "Error loading row 1 of table users: match_on column email of table users is not set")
+
+	// Markers whose value is not known before loading cannot be matched on
+	err = Validate([]byte(`
+- table: 'users'
+  match_on: ['email', 'created_at']
+  fields:
+    email: 'foo@example.com'
+    created_at: 'ON_INSERT_NOW()'
+`))
+	assert.EqualError(t, err, "Error loading row 1 of table users: match_on column created_at of table users is set to ON_INSERT_NOW()")
+	err = Validate([]byte(`
+- table: 'users'
+  match_on: ['id']
+  pk:
+    id: 'ON_INSERT_UUID()'
+`))
+	assert.EqualError(t, err, "Error loading row 1 of table users: match_on column id of table users is set to ON_INSERT_UUID()")
+	err = Validate([]byte(`
+- table: 'users'
+  match_on: ['email', 'tenant_id']
+  fields:
+    email: 'foo@example.com'
+    tenant_id: 'VAR(tenant)'
+`))
+	assert.Nil(t, err)
+
+	// Value of the wrong type
+	err = Validate([]byte(`
+- table: 'some_table'
+  pk:
+    id: 'one'
+  types:
+    id: int64
+`))
+	assert.EqualError(t, err, "Error loading row 1 of table some_table: column id of table some_table: cannot convert one to int64")
+}
+
+func TestValidateAll(t *testing.T) {
+	data := []byte(`
+- table: 'some_table'
+  pk:
+    id: 1
+- pk:
+    id: 2
+- table: 'some_table'
+  pk:
+    id: 3
+  fields:
+    id: 3
+- table: 'users'
+  match_on: ['email']
+  fields:
+    name: 'Foo'
+`)
+
+	// Validate stops at the first invalid row
+	assert.EqualError(t, Validate(data), "Error loading row 2: table is not set")
+
+	// Every invalid row is reported
+	err := ValidateAll(data)
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !assert.True(t, ok) {
+		return
+	}
+	errs := joined.Unwrap()
+	if assert.Len(t, errs, 3) {
+		assert.EqualError(t, errs[0], "Error loading row 2: table is not set")
+		assert.EqualError(t, errs[1], "Error loading row 3 of table some_table: column id of table some_table is set in both pk and fields")
+		assert.EqualError(t, errs[2], "Error loading row 4 of table users: match_on column email of table users is not set")
+	}
+	var processingErr *ProcessingError
+	assert.True(t, errors.As(err, &processingErr))
+	assert.Equal(t, 2, processingErr.Row)
+
+	// A valid fixture has nothing to report
+	assert.Nil(t, ValidateAll(data[:bytes.Index(data, []byte("- pk:"))]))
+}
+
+func TestDryRunWithEmptyFixtures(t *testing.T) {
+	// Empty files and empty lists load nothing
+	for _, data := range []string{"", "---\n", "[]"} {
+		c := &Context{DryRun: true, Truncate: true}
+		err := LoadWithContext(context.Background(), []byte(data), nil, "sqlite", c)
+		assert.Nil(t, err)
+		assert.Empty(t, c.Statements)
+	}
+
+	// Null entries are skipped with a warning
+	var logged bytes.Buffer
+	c := &Context{DryRun: true, Truncate: true, Logger: log.New(&logged, "", 0)}
+	err := LoadWithContext(context.Background(), []byte(`
+---
+-
+- table: 'some_table'
+  pk:
+    id: 1
+`), nil, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, "Skipping empty row 1\n", logged.String())
+	assert.Equal(t, []Statement{
+		{Query: `DELETE FROM "some_table"`},
+		{Query: `SELECT EXISTS(SELECT 1 FROM "some_table" WHERE "id" = ?)`, Args: []interface{}{1}},
+		{Query: `INSERT INTO "some_table"("id") VALUES(?)`, Args: []interface{}{1}},
+		{Query: `UPDATE "some_table" SET "id" = ? WHERE "id" = ?`, Args: []interface{}{1, 1}},
+	}, c.Statements)
+
+	// A row without any column is rejected
+	_, err = Render([]byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+- table: 'foo'
+`), "sqlite")
+	assert.EqualError(t, err, "Error loading row 2 of table foo: neither pk nor fields are set")
+}
+
+func TestDryRunWithTableMode(t *testing.T) {
+	data := []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+- table: 'other_table'
+  pk:
+    id: 2
+- table: 'join_table'
+  pk:
+    some_id: 1
+- table: 'string_key_table'
+  pk:
+    id: 'foo'
+`)
+
+	// Tables left to ModeDefault follow Upsert
+	c := &Context{DryRun: true, Upsert: true, TableMode: map[string]Mode{
+		"other_table":      ModeInsertOnly,
+		"join_table":       ModeUpdateOnly,
+		"string_key_table": ModeDefault,
+	}}
+	err := LoadWithContext(context.Background(), data, nil, "postgres", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		{Query: `INSERT INTO "some_table"("id") VALUES($1) ON CONFLICT ("id") DO UPDATE SET "id" = $2`, Args: []interface{}{1, 1}},
+		{Query: `INSERT INTO "other_table"("id") VALUES($1)`, Args: []interface{}{2}},
+		{Query: `SELECT EXISTS(SELECT 1 FROM "join_table" WHERE "some_id" = $1)`, Args: []interface{}{1}},
+		{Query: `UPDATE "join_table" SET "some_id" = $1 WHERE "some_id" = $2`, Args: []interface{}{1, 1}},
+		{Query: `INSERT INTO "string_key_table"("id") VALUES($1) ON CONFLICT ("id") DO UPDATE SET "id" = $2`, Args: []interface{}{"foo", "foo"}},
+	}, c.Statements)
+}
+
+func TestDryRunWithValueTransformer(t *testing.T) {
+	data := []byte(`
+---
+- table: 'users'
+  pk:
+    id: 1
+  fields:
+    email: 'foo@example.com'
+    name: 'Foo'
+    created_at: 'ON_INSERT_NOW()'
+  match_on: ['email']
+`)
+	now := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	// Only the email is transformed, wherever it is bound
+	c := &Context{DryRun: true, Now: func() time.Time { return now }, ValueTransformer: func(table, column string, value interface{}) (interface{}, error) {
+		if table == "users" && column == "email" {
+			return strings.ToUpper(value.(string)), nil
+		}
+		return value, nil
+	}}
+	err := LoadWithContext(context.Background(), data, nil, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		{Query: `SELECT EXISTS(SELECT 1 FROM "users" WHERE "email" = ?)`, Args: []interface{}{"FOO@EXAMPLE.COM"}},
+		{
+			Query: `INSERT INTO "users"("id", "created_at", "email", "name") VALUES(?, ?, ?, ?)`,
+			Args:  []interface{}{1, now, "FOO@EXAMPLE.COM", "Foo"},
+		},
+		{
+			Query: `UPDATE "users" SET "id" = ?, "email" = ?, "name" = ? WHERE "email" = ?`,
+			Args:  []interface{}{1, "FOO@EXAMPLE.COM", "Foo", "FOO@EXAMPLE.COM"},
+		},
+	}, c.Statements)
+
+	// The values of markers are bound as they are
+	markers := []byte(`
+---
+- table: 'users'
+  pk:
+    id: 'ON_INSERT_UUID()'
+  fields:
+    name: 'NULL()'
+    avatar: 'BYTES(Zm9v)'
+    email: 'foo@example.com'
+`)
+	c = &Context{DryRun: true, NewUUID: func() string { return "uuid" }, ValueTransformer: func(table, column string, value interface{}) (interface{}, error) {
+		return "transformed " + value.(string), nil
+	}}
+	err = LoadWithContext(context.Background(), markers, nil, "sqlite", c)
+	assert.Nil(t, err)
+	if assert.Len(t, c.Statements, 3) {
+		assert.Equal(t, `INSERT INTO "users"("id", "avatar", "email", "name") VALUES(?, ?, ?, ?)`, c.Statements[1].Query)
+		assert.Equal(t, []interface{}{"uuid", []byte("foo"), "transformed foo@example.com", nil}, c.Statements[1].Args)
+	}
+
+	// An error fails the row
+	c = &Context{DryRun: true, ValueTransformer: func(table, column string, value interface{}) (interface{}, error) {
+		if column == "name" {
+			return nil, errors.New("cannot encrypt")
+		}
+		return value, nil
+	}}
+	err = LoadWithContext(context.Background(), data, nil, "sqlite", c)
+	assert.EqualError(t, err, "Error loading row 1 of table users (column name): cannot encrypt")
+}
+
+// sqlStateError is a database error carrying a SQLSTATE code
+type sqlStateError string
+
+func (e sqlStateError) Error() string {
+	return "SQLSTATE " + string(e)
+}
+
+func (e sqlStateError) SQLState() string {
+	return string(e)
+}
+
+// flakyDriver is a database/sql driver whose statements fail with a
+// serialization failure as long as failures is above zero, queries find no
+// existing row. It counts the transactions begun and committed and records
+// every query
+type flakyDriver struct {
+	failures int
+	begun    int
+	commits  int
+	queries  []string
+}
+
+func (d *flakyDriver) Open(name string) (driver.Conn, error) {
+	return flakyConn{d}, nil
+}
+
+func (d *flakyDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	return d.Open("")
+}
+
+func (d *flakyDriver) Driver() driver.Driver {
+	return d
+}
+
+type flakyConn struct {
+	d *flakyDriver
+}
+
+func (c flakyConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.queries = append(c.d.queries, query)
+	return flakyStmt(c), nil
+}
+
+func (c flakyConn) Close() error {
+	return nil
+}
+
+func (c flakyConn) Begin() (driver.Tx, error) {
+	c.d.begun++
+	return flakyTx(c), nil
+}
+
+type flakyTx struct {
+	d *flakyDriver
+}
+
+func (tx flakyTx) Commit() error {
+	tx.d.commits++
+	return nil
+}
+
+func (tx flakyTx) Rollback() error {
+	return nil
+}
+
+type flakyStmt struct {
+	d *flakyDriver
+}
+
+func (s flakyStmt) Close() error {
+	return nil
+}
+
+func (s flakyStmt) NumInput() int {
+	return -1
+}
+
+func (s flakyStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.d.failures > 0 {
+		s.d.failures--
+		return nil, sqlStateError("40001")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s flakyStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &flakyRows{}, nil
+}
+
+type flakyRows struct {
+	done bool
+}
+
+func (r *flakyRows) Columns() []string {
+	return []string{"exists"}
+}
+
+func (r *flakyRows) Close() error {
+	return nil
+}
+
+func (r *flakyRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = false
+	return nil
+}
+
+func TestBatchFlushedBeforeRowWithReturning(t *testing.T) {
+	d := new(flakyDriver)
+	db := sql.OpenDB(d)
+	err := LoadWithContext(context.Background(), []byte(`
+---
+- table: 'parent'
+  pk:
+    id: 1
+- table: 'parent'
+  pk:
+    id: 2
+- table: 'child'
+  pk:
+    id: 3
+  fields:
+    parent_id: 2
+  returning: ['created_at']
+`), db, "sqlite", &Context{Batch: true})
+	assert.Nil(t, err)
+
+	// The batched parents are inserted before the child, which is read back
+	// on its own
+	assert.Equal(t, []string{
+		`SELECT EXISTS(SELECT 1 FROM "parent" WHERE "id" = ?)`,
+		`SELECT EXISTS(SELECT 1 FROM "parent" WHERE "id" = ?)`,
+		`SELECT EXISTS(SELECT 1 FROM "child" WHERE "id" = ?)`,
+		`INSERT INTO "parent"("id") VALUES(?), (?)`,
+		`INSERT INTO "child"("id", "parent_id") VALUES(?, ?) RETURNING "created_at"`,
+	}, d.queries)
+}
+
+func TestLoadWithRetry(t *testing.T) {
+	data := []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+`)
+	retry := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+
+	// A serialization failure is retried in a new transaction
+	d := &flakyDriver{failures: 1}
+	db := sql.OpenDB(d)
+	err := LoadWithContext(context.Background(), data, db, "sqlite", &Context{Retry: retry})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, d.begun)
+	assert.Equal(t, 1, d.commits)
+
+	// Without a retry policy the load fails at once
+	d = &flakyDriver{failures: 1}
+	db = sql.OpenDB(d)
+	err = LoadWithContext(context.Background(), data, db, "sqlite", new(Context))
+	assert.EqualError(t, errors.Unwrap(err), "SQLSTATE 40001")
+	assert.Equal(t, 1, d.begun)
+
+	// Nor is it attempted more than MaxAttempts times
+	d = &flakyDriver{failures: 5}
+	db = sql.OpenDB(d)
+	err = LoadWithContext(context.Background(), data, db, "sqlite", &Context{Retry: retry})
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, d.begun)
+	assert.Equal(t, 0, d.commits)
+
+	// Errors the classifier rejects are not retried
+	retry.Retryable = func(err error) bool { return false }
+	d = &flakyDriver{failures: 1}
+	db = sql.OpenDB(d)
+	err = LoadWithContext(context.Background(), data, db, "sqlite", &Context{Retry: retry})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, d.begun)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var p RetryPolicy
+
+	// Postgres serialization failures and deadlocks are transient
+	assert.True(t, p.retryable(&pq.Error{Code: "40001"}))
+	assert.True(t, p.retryable(&ProcessingError{Err: &pq.Error{Code: "40P01"}}))
+	assert.True(t, p.retryable(sqlStateError("40001")))
+	assert.False(t, p.retryable(&pq.Error{Code: "23505"}))
+	assert.False(t, p.retryable(errors.New("40001")))
+
+	// The backoff doubles after every attempt
+	p.Backoff = 10 * time.Millisecond
+	assert.Equal(t, 10*time.Millisecond, p.backoff(1))
+	assert.Equal(t, 40*time.Millisecond, p.backoff(3))
+}
+
+func TestDryRunClean(t *testing.T) {
+	data := []byte(`
+---
+- table: 'orders'
+  pk:
+    id: 1
+- table: 'users'
+  pk:
+    id: 1
+- table: '#'
+- table: 'orders'
+  pk:
+    id: 2
+`)
+
+	// Tables are emptied in reverse order of their first row
+	c := &Context{DryRun: true}
+	err := CleanWithContext(context.Background(), data, nil, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		{Query: `DELETE FROM "users"`},
+		{Query: `DELETE FROM "orders"`},
+	}, c.Statements)
+
+	// Or before the tables they depend on
+	c = &Context{DryRun: true, DependsOn: map[string][]string{"orders": {"users"}}}
+	err = CleanWithContext(context.Background(), data, nil, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		{Query: `DELETE FROM "orders"`},
+		{Query: `DELETE FROM "users"`},
+	}, c.Statements)
+}
+
+func TestDryRunWithTableTemplate(t *testing.T) {
+	data := []byte(`
+---
+- table: 'users_{tenant}'
+  pk:
+    id: 1
+  fields:
+    name: 'foo'
+`)
+	c := &Context{DryRun: true, Truncate: true, Vars: map[string]interface{}{"tenant": "t1"}}
+	err := LoadWithContext(context.Background(), data, nil, "postgres", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		{Query: `TRUNCATE TABLE "users_t1" RESTART IDENTITY CASCADE`},
+		{Query: `SELECT EXISTS(SELECT 1 FROM "users_t1" WHERE "id" = $1)`, Args: []interface{}{1}},
+		{Query: `INSERT INTO "users_t1"("id", "name") VALUES($1, $2)`, Args: []interface{}{1, "foo"}},
+		{Query: `UPDATE "users_t1" SET "id" = $1, "name" = $2 WHERE "id" = $3`, Args: []interface{}{1, "foo", 1}},
+	}, c.Statements)
+
+	// An unresolved variable fails the load, naming the row
+	c = &Context{DryRun: true}
+	err = LoadWithContext(context.Background(), data, nil, "postgres", c)
+	assert.EqualError(t, err, "Error loading row 1 of table users_{tenant}: unknown variable tenant used by table name")
+	assert.Empty(t, c.Statements)
+}
+
+func TestDryRunTruncateFollowsDependsOn(t *testing.T) {
+	data := []byte(`
+---
+- table: 'orders'
+  pk:
+    id: 1
+  fields:
+    user_id: 1
+- table: 'users'
+  pk:
+    id: 1
+`)
+
+	// Without dependencies tables are emptied in reverse order of their
+	// first row
+	c := &Context{DryRun: true, Truncate: true, AssumeEmpty: true}
+	err := LoadWithContext(context.Background(), data, nil, "sqlite", c)
+	assert.Nil(t, err)
+	if assert.Len(t, c.Statements, 4) {
+		assert.Equal(t, `DELETE FROM "users"`, c.Statements[0].Query)
+		assert.Equal(t, `DELETE FROM "orders"`, c.Statements[1].Query)
+	}
+
+	// Children are emptied before the tables they depend on
+	c = &Context{DryRun: true, Truncate: true, AssumeEmpty: true, DependsOn: map[string][]string{"orders": {"users"}}}
+	err = LoadWithContext(context.Background(), data, nil, "sqlite", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []Statement{
+		{Query: `DELETE FROM "orders"`},
+		{Query: `DELETE FROM "users"`},
+		{Query: `INSERT INTO "users"("id") VALUES(?)`, Args: []interface{}{1}},
+		{Query: `INSERT INTO "orders"("id", "user_id") VALUES(?, ?)`, Args: []interface{}{1, 1}},
+	}, c.Statements)
+}
+
+func TestConcurrentLoadsShareContext(t *testing.T) {
+	data := []byte(`
+---
+- table: 'some_table'
+  pk:
+    id: 1
+- table: 'some_table'
+  pk:
+    id: 2
+`)
+
+	// The Context is only read by loads, run with -race to check it
+	c := &Context{Batch: true}
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			errs <- LoadWithContext(context.Background(), data, sql.OpenDB(new(flakyDriver)), "sqlite", c)
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, <-errs)
+	}
+}
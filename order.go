@@ -0,0 +1,82 @@
+package fixtures
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// tableDepths returns the length of the longest chain of tables each table
+// of c.DependsOn depends on, tables are loaded in increasing depth. A cycle
+// in the dependencies is reported as an error naming its tables
+func (c *Context) tableDepths() (map[string]int, error) {
+	depths := make(map[string]int)
+	visiting := make(map[string]bool)
+
+	var visit func(table string, path []string) error
+	visit = func(table string, path []string) error {
+		if _, ok := depths[table]; ok {
+			return nil
+		}
+		path = append(path, table)
+		if visiting[table] {
+			for i, t := range path {
+				if t == table {
+					path = path[i:]
+					break
+				}
+			}
+			return fmt.Errorf("Cyclic table dependencies: %s", strings.Join(path, " -> "))
+		}
+		visiting[table] = true
+
+		depth := 0
+		for _, dependency := range c.DependsOn[table] {
+			if err := visit(dependency, path); err != nil {
+				return err
+			}
+			if depths[dependency]+1 > depth {
+				depth = depths[dependency] + 1
+			}
+		}
+		depths[table] = depth
+		return nil
+	}
+
+	// Visit the tables in a stable order so the same cycle is always reported
+	tables := make([]string, 0, len(c.DependsOn))
+	for table := range c.DependsOn {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		if err := visit(table, nil); err != nil {
+			return nil, err
+		}
+	}
+	return depths, nil
+}
+
+// rowOrder returns the indexes of rows in the order they have to be loaded,
+// rows of a table come after the rows of the tables it depends on and are
+// otherwise kept in fixture order. Deleted rows come first, before the rows of
+// the tables they depend on. Tables are compared by their resolved name
+func (c *Context) rowOrder(rows []Row, depths map[string]int) []int {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	if len(depths) > 0 {
+		sort.SliceStable(order, func(a, b int) bool {
+			rowA, rowB := &rows[order[a]], &rows[order[b]]
+			if rowA.Delete != rowB.Delete {
+				return rowA.Delete
+			}
+			if rowA.Delete {
+				return depths[c.table(rowA)] > depths[c.table(rowB)]
+			}
+			return depths[c.table(rowA)] < depths[c.table(rowB)]
+		})
+	}
+	return order
+}
@@ -0,0 +1,43 @@
+package fixtures
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableDepths(t *testing.T) {
+	c := &Context{DependsOn: map[string][]string{
+		"comments": {"posts", "users"},
+		"posts":    {"users"},
+	}}
+	depths, err := c.tableDepths()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"users": 0, "posts": 1, "comments": 2}, depths)
+
+	// Cycles are reported with the tables involved
+	c.DependsOn["users"] = []string{"comments"}
+	_, err = c.tableDepths()
+	assert.EqualError(t, err, "Cyclic table dependencies: comments -> posts -> users -> comments")
+}
+
+func TestRowOrder(t *testing.T) {
+	rows := []Row{
+		{Table: "comments"},
+		{Table: "posts"},
+		{Table: "comments"},
+		{Table: "tags"},
+		{Table: "posts"},
+	}
+
+	// Without dependencies the fixture order is kept
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, new(Context).rowOrder(rows, nil))
+
+	// Parent tables come first, rows of a table keep their order
+	depths := map[string]int{"posts": 0, "comments": 1}
+	assert.Equal(t, []int{1, 3, 4, 0, 2}, new(Context).rowOrder(rows, depths))
+
+	// Deleted rows come first, children before their parents
+	rows = append(rows, Row{Table: "posts", Delete: true}, Row{Table: "comments", Delete: true})
+	assert.Equal(t, []int{6, 5, 1, 3, 4, 0, 2}, new(Context).rowOrder(rows, depths))
+}
@@ -0,0 +1,452 @@
+package fixtures
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Statement is a query along with the values bound to its placeholders
+type Statement struct {
+	Query string
+	Args  []interface{}
+}
+
+// newStatement returns the statement running query with args
+func newStatement(query string, args []interface{}) Statement {
+	return Statement{Query: query, Args: args}
+}
+
+// Interpolated returns the query of the statement with its values written in
+// place of the placeholders, such as for pasting it into a SQL shell. It is
+// meant for logging and debugging only: values are quoted on a best-effort
+// basis and the result is not safe to execute
+func (s Statement) Interpolated() string {
+	var (
+		b     strings.Builder
+		next  int
+		quote rune
+	)
+	query := []rune(s.Query)
+	for i := 0; i < len(query); i++ {
+		r := query[i]
+		literal, end := "", i
+		switch {
+		case quote != 0:
+			// Placeholders within quotes are text
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			next++
+			literal, end = s.literal(next, ""), i+1
+		case r == '$':
+			end = scanDigits(query, i+1)
+			literal = s.literal(atoi(query[i+1:end]), "")
+		case r == ':' && i+1 < len(query) && query[i+1] == ':':
+			// A Postgres cast such as 'x'::text
+			b.WriteString("::")
+			i++
+			continue
+		case r == ':' || r == '@':
+			// Numbered as :1 or @p1, or else named as :name or @name
+			from := i + 1
+			if r == '@' && from < len(query) && query[from] == 'p' && scanDigits(query, from+1) > from+1 {
+				from++
+			}
+			if end = scanDigits(query, from); end > from {
+				literal = s.literal(atoi(query[from:end]), "")
+			} else if end = scanName(query, i+1); end > i+1 {
+				literal = s.literal(0, string(query[i+1:end]))
+			}
+		}
+		if literal == "" {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteString(literal)
+		i = end - 1
+	}
+	return b.String()
+}
+
+// scanDigits returns the index following the digits of query from from
+func scanDigits(query []rune, from int) int {
+	for from < len(query) && query[from] >= '0' && query[from] <= '9' {
+		from++
+	}
+	return from
+}
+
+// scanName returns the index following the identifier of query from from
+func scanName(query []rune, from int) int {
+	for from < len(query) && (query[from] == '_' || unicode.IsLetter(query[from]) || unicode.IsDigit(query[from])) {
+		from++
+	}
+	return from
+}
+
+// atoi returns the number written by digits, 0 when there are none
+func atoi(digits []rune) int {
+	n, _ := strconv.Atoi(string(digits))
+	return n
+}
+
+// literal returns the SQL literal of the n-th (1-based) value of the
+// statement, or of the value named name, an empty string when there is none
+func (s Statement) literal(n int, name string) string {
+	if name != "" {
+		for _, arg := range s.Args {
+			if named, ok := arg.(sql.NamedArg); ok && named.Name == name {
+				return sqlLiteral(named.Value)
+			}
+		}
+		return ""
+	}
+	if n < 1 || n > len(s.Args) {
+		return ""
+	}
+	if named, ok := s.Args[n-1].(sql.NamedArg); ok {
+		return sqlLiteral(named.Value)
+	}
+	return sqlLiteral(s.Args[n-1])
+}
+
+// sqlLiteral returns a value written as a SQL literal, strings and times are
+// quoted and bytes written as a hex literal
+func sqlLiteral(value interface{}) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v, err := valuer.Value(); err == nil {
+			value = v
+		}
+	}
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'"
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999999-07:00") + "'"
+	}
+	return "'" + strings.Replace(fmt.Sprint(value), "'", "''", -1) + "'"
+}
+
+// BuildExists returns a query selecting whether a row matching row exists
+// along with its values, row has to be initialised with Init first
+func BuildExists(row *Row, driver string) (string, []interface{}) {
+	return existsQuery(row, driver), row.GetMatchValues()
+}
+
+// BuildInsert returns an INSERT query for row along with its values
+func BuildInsert(row *Row, driver string) (string, []interface{}) {
+	values := row.GetInsertValues()
+	return insertQuery(row, driver) + returningClause(row, driver, len(values)), values
+}
+
+// BuildUpdate returns an UPDATE query for row along with its values
+func BuildUpdate(row *Row, driver string) (string, []interface{}) {
+	values := uniqueArgs(append(row.GetUpdateValues(), row.GetMatchValues()...))
+	return updateQuery(row, driver) + returningClause(row, driver, len(values)), values
+}
+
+// buildUpdateReusingArgs is like BuildUpdate but the where condition reuses
+// the placeholders of the match columns also set by the query rather than
+// binding their values again. Only numbered placeholders can be reused, values
+// bound by name are never bound twice anyway
+func buildUpdateReusingArgs(row *Row, driver string) (string, []interface{}) {
+	if row.named() || row.placeholder(driver, 1, "") == row.placeholder(driver, 2, "") {
+		return BuildUpdate(row, driver)
+	}
+
+	// Position of the value of every updated column bound to a placeholder
+	updated := make(map[string]int, len(row.updateColumns))
+	j := 0
+	for k, column := range row.updateColumns {
+		if _, ok := row.updateValues[k].(exprValue); !ok {
+			updated[column] = j
+			j++
+		}
+	}
+
+	values := row.GetUpdateValues()
+	matchValues := row.GetMatchValues()
+	matchColumns := row.GetMatchColumns(driver)
+	wheres := make([]string, len(matchColumns))
+	for k, column := range row.matchColumns {
+		j, ok := updated[column]
+		if !ok {
+			values = append(values, matchValues[k])
+			j = len(values) - 1
+		}
+		wheres[k] = fmt.Sprintf("%s = %s", matchColumns[k], row.placeholder(driver, j+1, column))
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET %s WHERE %s`,
+		quoteTable(row.quoter(driver), row.Table),
+		strings.Join(row.GetUpdatePlaceholders(driver), ", "),
+		strings.Join(wheres, " AND "),
+	)
+	return query + returningClause(row, driver, len(values)), values
+}
+
+// BuildDelete returns a DELETE query for row along with its values
+func BuildDelete(row *Row, driver string) (string, []interface{}) {
+	return deleteQuery(row, driver), row.GetMatchValues()
+}
+
+// BuildUpsert returns an INSERT query updating row when it already exists
+// along with its values, only Postgres and MySQL support it
+func BuildUpsert(row *Row, driver string) (string, []interface{}) {
+	values := uniqueArgs(append(row.GetInsertValues(), row.GetUpdateValues()...))
+	return upsertQuery(row, driver) + returningClause(row, driver, len(values)), values
+}
+
+// returningClause returns the RETURNING clause selecting the Returning
+// columns of row, or an empty string when it has none. Oracle returns them
+// INTO the placeholders following the n values of the query, see execRow
+func returningClause(row *Row, driver string, n int) string {
+	if len(row.Returning) == 0 {
+		return ""
+	}
+	returning := " RETURNING " + strings.Join(quoteIdentifiers(row.quoter(driver), row.columns(row.Returning)), ", ")
+	if driver != oracleDriver && driver != godrorDriver {
+		return returning
+	}
+	into := make([]string, len(row.Returning))
+	for k := range row.Returning {
+		into[k] = placeholder(driver, n+k+1)
+	}
+	return returning + " INTO " + strings.Join(into, ", ")
+}
+
+// existsQuery returns a query selecting whether a row matching row exists,
+// its values are the match values
+func existsQuery(row *Row, driver string) string {
+	exists := fmt.Sprintf(
+		`EXISTS(SELECT 1 FROM %s WHERE %s)`,
+		quoteTable(row.quoter(driver), row.Table),
+		row.GetMatchWhere(driver, 0),
+	)
+	return selectPredicates(driver, exists)
+}
+
+// buildExistsUnchanged returns a query selecting whether a row matching row
+// exists and whether it already holds the update values of row, along with
+// its values. ON_UPDATE_NOW() columns are not compared
+func buildExistsUnchanged(row *Row, driver string) (string, []interface{}) {
+	table := quoteTable(row.quoter(driver), row.Table)
+	matchValues := row.GetMatchValues()
+	values := append(append([]interface{}{}, matchValues...), matchValues...)
+
+	conditions := []string{row.GetMatchWhere(driver, len(matchValues))}
+	columns := row.GetUpdateColumns(driver)
+	for k, value := range row.updateValues {
+		switch v := value.(type) {
+		case nowValue:
+			continue
+		case exprValue:
+			conditions = append(conditions, nullSafeEqual(driver, columns[k], v.sql))
+		default:
+			values = append(values, row.resolveValues(row.updateColumns[k:k+1], []interface{}{value})...)
+			conditions = append(conditions, nullSafeEqual(driver, columns[k], row.placeholder(driver, len(values), row.updateColumns[k])))
+		}
+	}
+
+	exists := fmt.Sprintf(`EXISTS(SELECT 1 FROM %s WHERE %s)`, table, row.GetMatchWhere(driver, 0))
+	unchanged := fmt.Sprintf(`EXISTS(SELECT 1 FROM %s WHERE %s)`, table, strings.Join(conditions, " AND "))
+	return selectPredicates(driver, exists, unchanged), uniqueArgs(values)
+}
+
+// selectPredicates returns a query selecting the value of every predicate.
+// SQL Server and Oracle cannot select a predicate so they select 1 or 0
+// instead, Oracle also has to select FROM DUAL
+func selectPredicates(driver string, predicates ...string) string {
+	switch driver {
+	case sqlserverDriver, mssqlDriver, oracleDriver, godrorDriver:
+		selected := make([]string, len(predicates))
+		for i, predicate := range predicates {
+			selected[i] = fmt.Sprintf(`CASE WHEN %s THEN 1 ELSE 0 END`, predicate)
+		}
+		if driver == oracleDriver || driver == godrorDriver {
+			return fmt.Sprintf(`SELECT %s FROM DUAL`, strings.Join(selected, ", "))
+		}
+		return fmt.Sprintf(`SELECT %s`, strings.Join(selected, ", "))
+	}
+	return fmt.Sprintf(`SELECT %s`, strings.Join(predicates, ", "))
+}
+
+// nullSafeEqual returns a condition comparing a column to a value which
+// holds when both are NULL
+func nullSafeEqual(driver, column, value string) string {
+	switch driver {
+	case mysqlDriver:
+		return fmt.Sprintf(`%s <=> %s`, column, value)
+	case sqliteDriver, sqlite3Driver:
+		return fmt.Sprintf(`%s IS %s`, column, value)
+	case sqlserverDriver, mssqlDriver:
+		return fmt.Sprintf(`(%s = %s OR %s IS NULL AND %s IS NULL)`, column, value, column, value)
+	case oracleDriver, godrorDriver:
+		return fmt.Sprintf(`DECODE(%s, %s, 1, 0) = 1`, column, value)
+	}
+	return fmt.Sprintf(`%s IS NOT DISTINCT FROM %s`, column, value)
+}
+
+// insertQuery returns an INSERT query for row, its values are the insert
+// values
+func insertQuery(row *Row, driver string) string {
+	return fmt.Sprintf(
+		`INSERT INTO %s(%s)%s VALUES(%s)`,
+		quoteTable(row.quoter(driver), row.Table),
+		strings.Join(row.GetInsertColumns(driver), ", "),
+		overridingClause(row, driver),
+		strings.Join(row.GetInsertPlaceholders(driver), ", "),
+	)
+}
+
+// overridingClause returns the clause letting Postgres insert the values of
+// identity columns generated always, or an empty string when not needed
+func overridingClause(row *Row, driver string) string {
+	if driver == postgresDriver && row.OverrideSystemValue {
+		return " OVERRIDING SYSTEM VALUE"
+	}
+	return ""
+}
+
+// updateQuery returns an UPDATE query for row, its values are the update
+// values followed by the match values
+func updateQuery(row *Row, driver string) string {
+	return fmt.Sprintf(
+		`UPDATE %s SET %s WHERE %s`,
+		quoteTable(row.quoter(driver), row.Table),
+		strings.Join(row.GetUpdatePlaceholders(driver), ", "),
+		row.GetMatchWhere(driver, len(row.GetUpdateValues())),
+	)
+}
+
+// deleteQuery returns a DELETE query for row, its values are the match
+// values
+func deleteQuery(row *Row, driver string) string {
+	return fmt.Sprintf(
+		`DELETE FROM %s WHERE %s`,
+		quoteTable(row.quoter(driver), row.Table),
+		row.GetMatchWhere(driver, 0),
+	)
+}
+
+// supportsUpsert reports whether driver can insert or update a row with a
+// single statement
+func supportsUpsert(driver string) bool {
+	return driver == postgresDriver || driver == mysqlDriver
+}
+
+// upsertQuery returns an INSERT query which updates the row instead when its
+// primary key, or match_on columns, already exist. Postgres conflicts on the
+// conflict_columns when they are set, MySQL on any unique key. Its values are
+// the insert values followed by the update values. A row with nothing to
+// update is left as is, MySQL setting its first match column to itself
+func upsertQuery(row *Row, driver string) string {
+	updates := strings.Join(
+		row.updatePlaceholders(driver, len(row.GetInsertValues())),
+		", ",
+	)
+	if driver == mysqlDriver {
+		if updates == "" {
+			column := row.GetConflictColumns(driver)[0]
+			updates = fmt.Sprintf("%s = %s", column, column)
+		}
+		return fmt.Sprintf(`%s ON DUPLICATE KEY UPDATE %s`, insertQuery(row, driver), updates)
+	}
+	if updates == "" {
+		return fmt.Sprintf(
+			`%s ON CONFLICT (%s) DO NOTHING`,
+			insertQuery(row, driver),
+			strings.Join(row.GetConflictColumns(driver), ", "),
+		)
+	}
+	return fmt.Sprintf(
+		`%s ON CONFLICT (%s) DO UPDATE SET %s`,
+		insertQuery(row, driver),
+		strings.Join(row.GetConflictColumns(driver), ", "),
+		updates,
+	)
+}
+
+// deferConstraintsQueries returns the query deferring foreign key checks
+// until the end of a transaction and the query checking them again, or
+// empty queries when driver cannot defer them. Postgres only defers
+// constraints declared DEFERRABLE while MySQL skips the checks altogether.
+// SQLite stops checking the deferred constraints when the pragma is turned
+// off, see checkSQLiteForeignKeys
+func deferConstraintsQueries(driver string) (deferQuery, restoreQuery string) {
+	switch driver {
+	case postgresDriver, oracleDriver, godrorDriver:
+		return "SET CONSTRAINTS ALL DEFERRED", "SET CONSTRAINTS ALL IMMEDIATE"
+	case mysqlDriver:
+		return "SET FOREIGN_KEY_CHECKS=0", "SET FOREIGN_KEY_CHECKS=1"
+	case sqliteDriver, sqlite3Driver:
+		return "PRAGMA defer_foreign_keys = ON", "PRAGMA defer_foreign_keys = OFF"
+	}
+	return "", ""
+}
+
+// savepointQueries returns the queries setting a savepoint, rolling back to
+// it and releasing it. SQL Server names savepoints with SAVE TRANSACTION,
+// neither SQL Server nor Oracle have a way to release them
+func savepointQueries(driver, name string) (saveQuery, rollbackQuery, releaseQuery string) {
+	switch driver {
+	case sqlserverDriver, mssqlDriver:
+		return "SAVE TRANSACTION " + name, "ROLLBACK TRANSACTION " + name, ""
+	case oracleDriver, godrorDriver:
+		return "SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, ""
+	}
+	return "SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, "RELEASE SAVEPOINT " + name
+}
+
+// truncateQueries returns the queries emptying tables, quoting their names
+// with quote. Postgres truncates them in one statement, resetting their
+// sequences and cascading to tables referencing them. Other drivers delete
+// from the tables in reverse order, tables are given parents before children,
+// as MySQL's TRUNCATE would implicitly commit the transaction and SQLite has
+// no TRUNCATE at all
+func truncateQueries(tables []string, driver string, quote func(string) string) []string {
+	if len(tables) == 0 {
+		return nil
+	}
+
+	if driver == postgresDriver {
+		return []string{fmt.Sprintf(
+			`TRUNCATE TABLE %s RESTART IDENTITY CASCADE`,
+			strings.Join(quoteTables(quote, tables), ", "),
+		)}
+	}
+
+	return deleteQueries(tables, quote)
+}
+
+// deleteQueries returns the queries deleting every row of tables in reverse
+// order, quoting their names with quote
+func deleteQueries(tables []string, quote func(string) string) []string {
+	queries := make([]string, len(tables))
+	for i := range tables {
+		queries[i] = fmt.Sprintf(
+			`DELETE FROM %s`,
+			quoteTable(quote, tables[len(tables)-1-i]),
+		)
+	}
+	return queries
+}
@@ -0,0 +1,565 @@
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpsertQuery(t *testing.T) {
+	row := &Row{
+		Table: "some_table",
+		PK: map[string]interface{}{
+			"id": interface{}(1),
+		},
+		Fields: map[string]interface{}{
+			"string_field": interface{}("foobar"),
+			"created_at":   interface{}("ON_INSERT_NOW()"),
+			"updated_at":   interface{}("ON_UPDATE_NOW()"),
+		},
+	}
+	row.Init()
+
+	// Postgres uses ON CONFLICT with placeholders numbered after the insert
+	assert.Equal(
+		t,
+		`INSERT INTO "some_table"("id", "created_at", "string_field") VALUES($1, $2, $3) `+
+			`ON CONFLICT ("id") DO UPDATE SET "id" = $4, "string_field" = $5, "updated_at" = $6`,
+		upsertQuery(row, "postgres"),
+	)
+
+	// MySQL uses ON DUPLICATE KEY UPDATE
+	assert.Equal(
+		t,
+		"INSERT INTO `some_table`(`id`, `created_at`, `string_field`) VALUES(?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE `id` = ?, `string_field` = ?, `updated_at` = ?",
+		upsertQuery(row, "mysql"),
+	)
+
+	// A row with nothing to update is left as is
+	row = &Row{Table: "users", PK: map[string]interface{}{"id": 1}, OverrideSystemValue: true}
+	row.Init()
+	assert.Equal(t, `INSERT INTO "users"("id") OVERRIDING SYSTEM VALUE VALUES($1) ON CONFLICT ("id") DO NOTHING`, upsertQuery(row, "postgres"))
+	assert.Equal(t, "INSERT INTO `users`(`id`) VALUES(?) ON DUPLICATE KEY UPDATE `id` = `id`", upsertQuery(row, "mysql"))
+
+	assert.True(t, supportsUpsert("postgres"))
+	assert.True(t, supportsUpsert("mysql"))
+	assert.False(t, supportsUpsert("sqlite"))
+}
+
+func TestQueriesWithMatchOn(t *testing.T) {
+	row := &Row{
+		Table: "users",
+		Fields: map[string]interface{}{
+			"email": interface{}("foo@example.com"),
+			"name":  interface{}("Foo"),
+		},
+		MatchOn: []string{"email"},
+	}
+	row.Init()
+
+	assert.Equal(t, `SELECT EXISTS(SELECT 1 FROM "users" WHERE "email" = $1)`, existsQuery(row, "postgres"))
+	assert.Equal(t, `UPDATE "users" SET "email" = $1, "name" = $2 WHERE "email" = $3`, updateQuery(row, "postgres"))
+	assert.Equal(t, `DELETE FROM "users" WHERE "email" = $1`, deleteQuery(row, "postgres"))
+	assert.Equal(
+		t,
+		`INSERT INTO "users"("email", "name") VALUES($1, $2) `+
+			`ON CONFLICT ("email") DO UPDATE SET "email" = $3, "name" = $4`,
+		upsertQuery(row, "postgres"),
+	)
+}
+
+func TestUpsertQueryWithConflictColumns(t *testing.T) {
+	row := &Row{
+		Table: "users",
+		PK:    map[string]interface{}{"id": interface{}(1)},
+		Fields: map[string]interface{}{
+			"email": interface{}("foo@example.com"),
+			"name":  interface{}("Foo"),
+		},
+		ConflictColumns: []string{"email"},
+	}
+	row.Init()
+
+	// Rows are still looked up by their primary key
+	assert.Equal(t, `SELECT EXISTS(SELECT 1 FROM "users" WHERE "id" = $1)`, existsQuery(row, "postgres"))
+	assert.Equal(
+		t,
+		`INSERT INTO "users"("id", "email", "name") VALUES($1, $2, $3) `+
+			`ON CONFLICT ("email") DO UPDATE SET "id" = $4, "email" = $5, "name" = $6`,
+		upsertQuery(row, "postgres"),
+	)
+
+	// MySQL has no conflict target
+	assert.Equal(
+		t,
+		"INSERT INTO `users`(`id`, `email`, `name`) VALUES(?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE `id` = ?, `email` = ?, `name` = ?",
+		upsertQuery(row, "mysql"),
+	)
+
+	// The conflict_columns need a value
+	row = &Row{
+		Table:           "users",
+		PK:              map[string]interface{}{"id": interface{}(1)},
+		ConflictColumns: []string{"email"},
+	}
+	assert.EqualError(t, row.Validate(), "conflict_columns column email of table users is not set")
+}
+
+func TestBuildQueries(t *testing.T) {
+	row := &Row{
+		Table: "some_table",
+		PK: map[string]interface{}{
+			"id": interface{}(1),
+		},
+		Fields: map[string]interface{}{
+			"string_field":  interface{}("foobar"),
+			"boolean_field": interface{}(true),
+		},
+	}
+	row.Init()
+
+	var (
+		query string
+		args  []interface{}
+	)
+
+	// Postgres
+	query, args = BuildExists(row, "postgres")
+	assert.Equal(t, `SELECT EXISTS(SELECT 1 FROM "some_table" WHERE "id" = $1)`, query)
+	assert.Equal(t, []interface{}{1}, args)
+	query, args = BuildInsert(row, "postgres")
+	assert.Equal(t, `INSERT INTO "some_table"("id", "boolean_field", "string_field") VALUES($1, $2, $3)`, query)
+	assert.Equal(t, []interface{}{1, true, "foobar"}, args)
+	query, args = BuildUpdate(row, "postgres")
+	assert.Equal(t, `UPDATE "some_table" SET "id" = $1, "boolean_field" = $2, "string_field" = $3 WHERE "id" = $4`, query)
+	assert.Equal(t, []interface{}{1, true, "foobar", 1}, args)
+	query, args = BuildDelete(row, "postgres")
+	assert.Equal(t, `DELETE FROM "some_table" WHERE "id" = $1`, query)
+	assert.Equal(t, []interface{}{1}, args)
+
+	// MySQL
+	query, _ = BuildExists(row, "mysql")
+	assert.Equal(t, "SELECT EXISTS(SELECT 1 FROM `some_table` WHERE `id` = ?)", query)
+	query, _ = BuildInsert(row, "mysql")
+	assert.Equal(t, "INSERT INTO `some_table`(`id`, `boolean_field`, `string_field`) VALUES(?, ?, ?)", query)
+	query, _ = BuildUpdate(row, "mysql")
+	assert.Equal(t, "UPDATE `some_table` SET `id` = ?, `boolean_field` = ?, `string_field` = ? WHERE `id` = ?", query)
+	query, _ = BuildDelete(row, "mysql")
+	assert.Equal(t, "DELETE FROM `some_table` WHERE `id` = ?", query)
+	query, args = BuildUpsert(row, "mysql")
+	assert.Equal(
+		t,
+		"INSERT INTO `some_table`(`id`, `boolean_field`, `string_field`) VALUES(?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE `id` = ?, `boolean_field` = ?, `string_field` = ?",
+		query,
+	)
+	assert.Equal(t, []interface{}{1, true, "foobar", 1, true, "foobar"}, args)
+}
+
+func TestQueriesWithSchemaQualifiedTable(t *testing.T) {
+	row := &Row{
+		Table: "billing.invoices",
+		PK: map[string]interface{}{
+			"id": interface{}(1),
+		},
+		Fields: map[string]interface{}{
+			"amount": interface{}(100),
+		},
+	}
+	row.Init()
+
+	assert.Equal(t, `SELECT EXISTS(SELECT 1 FROM "billing"."invoices" WHERE "id" = $1)`, existsQuery(row, "postgres"))
+	assert.Equal(t, `INSERT INTO "billing"."invoices"("id", "amount") VALUES($1, $2)`, insertQuery(row, "postgres"))
+	assert.Equal(t, `UPDATE "billing"."invoices" SET "id" = $1, "amount" = $2 WHERE "id" = $3`, updateQuery(row, "postgres"))
+	assert.Equal(t, "DELETE FROM `billing`.`invoices` WHERE `id` = ?", deleteQuery(row, "mysql"))
+	assert.Equal(
+		t,
+		[]string{`TRUNCATE TABLE "billing"."invoices", "users" RESTART IDENTITY CASCADE`},
+		truncateQueries([]string{"billing.invoices", "users"}, "postgres", driverQuoter("postgres")),
+	)
+}
+
+func TestDeferConstraintsQueries(t *testing.T) {
+	deferQuery, restoreQuery := deferConstraintsQueries("postgres")
+	assert.Equal(t, "SET CONSTRAINTS ALL DEFERRED", deferQuery)
+	assert.Equal(t, "SET CONSTRAINTS ALL IMMEDIATE", restoreQuery)
+
+	deferQuery, restoreQuery = deferConstraintsQueries("mysql")
+	assert.Equal(t, "SET FOREIGN_KEY_CHECKS=0", deferQuery)
+	assert.Equal(t, "SET FOREIGN_KEY_CHECKS=1", restoreQuery)
+
+	deferQuery, _ = deferConstraintsQueries("sqlite3")
+	assert.Equal(t, "PRAGMA defer_foreign_keys = ON", deferQuery)
+
+	// Unknown drivers are left alone
+	deferQuery, restoreQuery = deferConstraintsQueries("sqlserver")
+	assert.Equal(t, "", deferQuery)
+	assert.Equal(t, "", restoreQuery)
+}
+
+func TestQueriesForSQLServer(t *testing.T) {
+	row := &Row{
+		Table: "dbo.some_table",
+		PK: map[string]interface{}{
+			"id": interface{}(1),
+		},
+		Fields: map[string]interface{}{
+			"string_field":  interface{}("foobar"),
+			"boolean_field": interface{}(true),
+		},
+	}
+	row.Init()
+
+	// Both driver names use @p placeholders and square brackets
+	for _, driver := range []string{"sqlserver", "mssql"} {
+		query, _ := BuildExists(row, driver)
+		assert.Equal(t, "SELECT CASE WHEN EXISTS(SELECT 1 FROM [dbo].[some_table] WHERE [id] = @p1) THEN 1 ELSE 0 END", query)
+		query, _ = BuildInsert(row, driver)
+		assert.Equal(t, "INSERT INTO [dbo].[some_table]([id], [boolean_field], [string_field]) VALUES(@p1, @p2, @p3)", query)
+		query, _ = BuildUpdate(row, driver)
+		assert.Equal(t, "UPDATE [dbo].[some_table] SET [id] = @p1, [boolean_field] = @p2, [string_field] = @p3 WHERE [id] = @p4", query)
+		query, _ = BuildDelete(row, driver)
+		assert.Equal(t, "DELETE FROM [dbo].[some_table] WHERE [id] = @p1", query)
+		assert.False(t, supportsUpsert(driver))
+	}
+}
+
+func TestSavepointQueries(t *testing.T) {
+	saveQuery, rollbackQuery, releaseQuery := savepointQueries("postgres", "fixtures_row")
+	assert.Equal(t, "SAVEPOINT fixtures_row", saveQuery)
+	assert.Equal(t, "ROLLBACK TO SAVEPOINT fixtures_row", rollbackQuery)
+	assert.Equal(t, "RELEASE SAVEPOINT fixtures_row", releaseQuery)
+
+	// SQL Server savepoints cannot be released
+	saveQuery, rollbackQuery, releaseQuery = savepointQueries("sqlserver", "fixtures_row")
+	assert.Equal(t, "SAVE TRANSACTION fixtures_row", saveQuery)
+	assert.Equal(t, "ROLLBACK TRANSACTION fixtures_row", rollbackQuery)
+	assert.Equal(t, "", releaseQuery)
+}
+
+// placeholderCount returns the number of distinct placeholders of query
+func placeholderCount(query string) int {
+	if n := strings.Count(query, "?"); n > 0 {
+		return n
+	}
+	return len(distinct(regexp.MustCompile(`\$\d+|@p\d+`).FindAllString(query, -1)))
+}
+
+// distinct returns the distinct values of values
+func distinct(values []string) map[string]bool {
+	seen := make(map[string]bool)
+	for _, value := range values {
+		seen[value] = true
+	}
+	return seen
+}
+
+func TestUpdateArgsMatchPlaceholders(t *testing.T) {
+	rows := []*Row{
+		{
+			Table:  "some_table",
+			PK:     map[string]interface{}{"id": 1},
+			Fields: map[string]interface{}{"string_field": "foobar", "updated_at": "ON_UPDATE_NOW()", "created_at": "ON_INSERT_NOW()"},
+		},
+		{
+			Table: "join_table",
+			PK:    map[string]interface{}{"some_id": 1, "other_id": 2},
+		},
+		{
+			Table:   "users",
+			Fields:  map[string]interface{}{"email": "foo@example.com", "name": "Foo"},
+			MatchOn: []string{"email"},
+		},
+	}
+
+	for _, row := range rows {
+		row.Init()
+		for _, driver := range []string{"postgres", "mysql", "sqlite", "sqlserver"} {
+			query, args := BuildUpdate(row, driver)
+			assert.Equal(t, placeholderCount(query), len(args), query)
+			query, args = buildUpdateReusingArgs(row, driver)
+			assert.Equal(t, placeholderCount(query), len(args), query)
+		}
+	}
+
+	// The primary key reuses the placeholders of its SET values
+	query, args := buildUpdateReusingArgs(rows[1], "postgres")
+	assert.Equal(t, `UPDATE "join_table" SET "other_id" = $1, "some_id" = $2 WHERE "other_id" = $1 AND "some_id" = $2`, query)
+	assert.Equal(t, []interface{}{2, 1}, args)
+
+	// Placeholders cannot be reused with ?
+	query, args = buildUpdateReusingArgs(rows[1], "mysql")
+	assert.Equal(t, "UPDATE `join_table` SET `other_id` = ?, `some_id` = ? WHERE `other_id` = ? AND `some_id` = ?", query)
+	assert.Equal(t, []interface{}{2, 1, 2, 1}, args)
+}
+
+func TestUpdateArgsWithVars(t *testing.T) {
+	row := &Row{
+		Table:         "some_table",
+		PK:            map[string]interface{}{"id": "VAR(id)"},
+		Fields:        map[string]interface{}{"string_field": "foobar"},
+		UpdateColumns: []string{"string_field"},
+	}
+	row.vars = map[string]interface{}{"id": 7}
+	row.Init()
+
+	// The primary key is not updated, its resolved value is bound once more
+	query, args := buildUpdateReusingArgs(row, "postgres")
+	assert.Equal(t, `UPDATE "some_table" SET "string_field" = $1 WHERE "id" = $2`, query)
+	assert.Equal(t, []interface{}{"foobar", 7}, args)
+}
+
+func TestQueriesWithExpr(t *testing.T) {
+	row := &Row{
+		Table: "some_table",
+		PK: map[string]interface{}{
+			"id": interface{}(1),
+		},
+		Fields: map[string]interface{}{
+			"created_at":   interface{}("EXPR(now() + interval '1 day')"),
+			"location":     interface{}("EXPR(point(1, 2))"),
+			"string_field": interface{}("foobar"),
+		},
+	}
+	row.Init()
+
+	var (
+		query string
+		args  []interface{}
+	)
+
+	// Expressions are written as is, only the other values are bound
+	query, args = BuildInsert(row, "postgres")
+	assert.Equal(t, `INSERT INTO "some_table"("id", "created_at", "location", "string_field") VALUES($1, now() + interval '1 day', point(1, 2), $2)`, query)
+	assert.Equal(t, []interface{}{1, "foobar"}, args)
+	query, args = BuildUpdate(row, "postgres")
+	assert.Equal(t, `UPDATE "some_table" SET "id" = $1, "created_at" = now() + interval '1 day', "location" = point(1, 2), "string_field" = $2 WHERE "id" = $3`, query)
+	assert.Equal(t, []interface{}{1, "foobar", 1}, args)
+	query, args = buildUpdateReusingArgs(row, "postgres")
+	assert.Equal(t, `UPDATE "some_table" SET "id" = $1, "created_at" = now() + interval '1 day', "location" = point(1, 2), "string_field" = $2 WHERE "id" = $1`, query)
+	assert.Equal(t, []interface{}{1, "foobar"}, args)
+	query, args = BuildUpsert(row, "postgres")
+	assert.Equal(
+		t,
+		`INSERT INTO "some_table"("id", "created_at", "location", "string_field") VALUES($1, now() + interval '1 day', point(1, 2), $2) `+
+			`ON CONFLICT ("id") DO UPDATE SET "id" = $3, "created_at" = now() + interval '1 day', "location" = point(1, 2), "string_field" = $4`,
+		query,
+	)
+	assert.Equal(t, []interface{}{1, "foobar", 1, "foobar"}, args)
+
+	// Rows are never looked up by an expression
+	row = &Row{Table: "some_table", PK: map[string]interface{}{"id": "EXPR(nextval('seq'))"}}
+	assert.EqualError(t, row.Validate(), "pk column id of table some_table is set to an EXPR()")
+	row = &Row{Table: "users", Fields: map[string]interface{}{"email": "EXPR(lower('A'))"}, MatchOn: []string{"email"}}
+	assert.EqualError(t, row.Validate(), "match_on column email of table users is set to an EXPR()")
+}
+
+func TestQueriesWithCompositePK(t *testing.T) {
+	row := &Row{
+		Table: "join_table",
+		PK: map[string]interface{}{
+			"some_id":  interface{}(1),
+			"other_id": interface{}(2),
+		},
+		Fields: map[string]interface{}{
+			"note": interface{}("foobar"),
+		},
+	}
+	row.Init()
+
+	// Primary key values follow the order of the primary key columns
+	assert.Equal(t, []string{`"other_id"`, `"some_id"`}, row.GetPKColumns("postgres"))
+	assert.Equal(t, []interface{}{2, 1}, row.GetPKValues())
+
+	// Standalone where clause numbered from $1
+	assert.Equal(t, `"other_id" = $1 AND "some_id" = $2`, row.GetWhere("postgres", 0))
+
+	// Insert path: the existence check binds the primary key values
+	query, args := BuildExists(row, "postgres")
+	assert.Equal(t, `SELECT EXISTS(SELECT 1 FROM "join_table" WHERE "other_id" = $1 AND "some_id" = $2)`, query)
+	assert.Equal(t, []interface{}{2, 1}, args)
+	query, args = BuildInsert(row, "postgres")
+	assert.Equal(t, `INSERT INTO "join_table"("other_id", "some_id", "note") VALUES($1, $2, $3)`, query)
+	assert.Equal(t, []interface{}{2, 1, "foobar"}, args)
+
+	// Update path: the where clause is numbered after the updated values
+	query, args = BuildUpdate(row, "postgres")
+	assert.Equal(
+		t,
+		`UPDATE "join_table" SET "other_id" = $1, "some_id" = $2, "note" = $3 WHERE "other_id" = $4 AND "some_id" = $5`,
+		query,
+	)
+	assert.Equal(t, []interface{}{2, 1, "foobar", 2, 1}, args)
+	query, args = BuildUpdate(row, "sqlserver")
+	assert.Equal(
+		t,
+		`UPDATE [join_table] SET [other_id] = @p1, [some_id] = @p2, [note] = @p3 WHERE [other_id] = @p4 AND [some_id] = @p5`,
+		query,
+	)
+	assert.Equal(t, []interface{}{2, 1, "foobar", 2, 1}, args)
+}
+
+func TestQueriesWithReturning(t *testing.T) {
+	row := &Row{
+		Table:     "users",
+		MatchOn:   []string{"email"},
+		Fields:    map[string]interface{}{"email": "foo@example.com"},
+		Returning: []string{"id", "created_at"},
+	}
+	row.Init()
+
+	query, _ := BuildInsert(row, "postgres")
+	assert.Equal(t, `INSERT INTO "users"("email") VALUES($1) RETURNING "id", "created_at"`, query)
+	query, _ = BuildUpdate(row, "postgres")
+	assert.Equal(t, `UPDATE "users" SET "email" = $1 WHERE "email" = $2 RETURNING "id", "created_at"`, query)
+	query, _ = buildUpdateReusingArgs(row, "postgres")
+	assert.Equal(t, `UPDATE "users" SET "email" = $1 WHERE "email" = $1 RETURNING "id", "created_at"`, query)
+	query, _ = BuildUpsert(row, "postgres")
+	assert.Equal(
+		t,
+		`INSERT INTO "users"("email") VALUES($1) ON CONFLICT ("email") DO UPDATE SET "email" = $2 RETURNING "id", "created_at"`,
+		query,
+	)
+
+	// Oracle returns the columns into output parameters following the values
+	query, _ = BuildInsert(row, "oracle")
+	assert.Equal(t, `INSERT INTO "USERS"("EMAIL") VALUES(:1) RETURNING "ID", "CREATED_AT" INTO :2, :3`, query)
+	query, _ = BuildUpdate(row, "oracle")
+	assert.Equal(t, `UPDATE "USERS" SET "EMAIL" = :1 WHERE "EMAIL" = :2 RETURNING "ID", "CREATED_AT" INTO :3, :4`, query)
+
+	// Nothing is returned by default
+	row.Returning = nil
+	query, _ = BuildInsert(row, "postgres")
+	assert.Equal(t, `INSERT INTO "users"("email") VALUES($1)`, query)
+}
+
+func TestQueriesWithOverrideSystemValue(t *testing.T) {
+	row := &Row{
+		Table:               "users",
+		PK:                  map[string]interface{}{"id": 1},
+		Fields:              map[string]interface{}{"name": "Foo"},
+		OverrideSystemValue: true,
+	}
+	row.Init()
+
+	// Identity values are inserted but never updated
+	query, args := BuildInsert(row, "postgres")
+	assert.Equal(t, `INSERT INTO "users"("id", "name") OVERRIDING SYSTEM VALUE VALUES($1, $2)`, query)
+	assert.Equal(t, []interface{}{1, "Foo"}, args)
+	query, args = BuildUpdate(row, "postgres")
+	assert.Equal(t, `UPDATE "users" SET "name" = $1 WHERE "id" = $2`, query)
+	assert.Equal(t, []interface{}{"Foo", 1}, args)
+	query, _ = BuildUpsert(row, "postgres")
+	assert.Equal(
+		t,
+		`INSERT INTO "users"("id", "name") OVERRIDING SYSTEM VALUE VALUES($1, $2) ON CONFLICT ("id") DO UPDATE SET "name" = $3`,
+		query,
+	)
+	assert.Equal(
+		t,
+		`INSERT INTO "users"("id", "name") OVERRIDING SYSTEM VALUE VALUES($1, $2), ($3, $4)`,
+		batchInsertQuery([]Row{*row, *row}, "postgres"),
+	)
+
+	// Other drivers have no such clause
+	query, _ = BuildInsert(row, "mysql")
+	assert.Equal(t, "INSERT INTO `users`(`id`, `name`) VALUES(?, ?)", query)
+
+	// A row with nothing but its identity has nothing to update
+	row = &Row{Table: "users", PK: map[string]interface{}{"id": 1}, OverrideSystemValue: true}
+	row.Init()
+	assert.Equal(t, 0, row.GetUpdateColumnsLength())
+	query, _ = BuildUpsert(row, "postgres")
+	assert.Equal(t, `INSERT INTO "users"("id") OVERRIDING SYSTEM VALUE VALUES($1) ON CONFLICT ("id") DO NOTHING`, query)
+}
+
+func TestExistsUnchangedQuery(t *testing.T) {
+	row := &Row{
+		Table: "some_table",
+		PK:    map[string]interface{}{"id": 1},
+		Fields: map[string]interface{}{
+			"string_field": "foobar",
+			"point":        "EXPR(point(1, 2))",
+			"updated_at":   "ON_UPDATE_NOW()",
+		},
+	}
+	row.Init()
+
+	// The match values are bound twice, followed by the compared values
+	query, args := buildExistsUnchanged(row, "postgres")
+	assert.Equal(
+		t,
+		`SELECT EXISTS(SELECT 1 FROM "some_table" WHERE "id" = $1), `+
+			`EXISTS(SELECT 1 FROM "some_table" WHERE "id" = $2 AND "id" IS NOT DISTINCT FROM $3 `+
+			`AND "point" IS NOT DISTINCT FROM point(1, 2) AND "string_field" IS NOT DISTINCT FROM $4)`,
+		query,
+	)
+	assert.Equal(t, []interface{}{1, 1, 1, "foobar"}, args)
+
+	query, _ = buildExistsUnchanged(row, "mysql")
+	assert.Equal(
+		t,
+		"SELECT EXISTS(SELECT 1 FROM `some_table` WHERE `id` = ?), "+
+			"EXISTS(SELECT 1 FROM `some_table` WHERE `id` = ? AND `id` <=> ? "+
+			"AND `point` <=> point(1, 2) AND `string_field` <=> ?)",
+		query,
+	)
+	query, _ = buildExistsUnchanged(row, "sqlite")
+	assert.Equal(
+		t,
+		`SELECT EXISTS(SELECT 1 FROM "some_table" WHERE "id" = ?), `+
+			`EXISTS(SELECT 1 FROM "some_table" WHERE "id" = ? AND "id" IS ? `+
+			`AND "point" IS point(1, 2) AND "string_field" IS ?)`,
+		query,
+	)
+	query, _ = buildExistsUnchanged(row, "sqlserver")
+	assert.Equal(
+		t,
+		`SELECT CASE WHEN EXISTS(SELECT 1 FROM [some_table] WHERE [id] = @p1) THEN 1 ELSE 0 END, `+
+			`CASE WHEN EXISTS(SELECT 1 FROM [some_table] WHERE [id] = @p2 AND ([id] = @p3 OR [id] IS NULL AND @p3 IS NULL) `+
+			`AND ([point] = point(1, 2) OR [point] IS NULL AND point(1, 2) IS NULL) `+
+			`AND ([string_field] = @p4 OR [string_field] IS NULL AND @p4 IS NULL)) THEN 1 ELSE 0 END`,
+		query,
+	)
+}
+
+func TestStatementInterpolated(t *testing.T) {
+	now := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	c := &Context{DryRun: true, AssumeEmpty: true, Now: func() time.Time { return now }}
+	err := LoadWithContext(context.Background(), []byte(`
+---
+- table: 'users'
+  pk:
+    id: 1
+  fields:
+    name: "O'Brien"
+    created_at: 'ON_INSERT_NOW()'
+`), nil, "postgres", c)
+	assert.Nil(t, err)
+	if assert.Len(t, c.Statements, 1) {
+		assert.Equal(
+			t,
+			`INSERT INTO "users"("id", "created_at", "name") VALUES(1, '2023-01-02 15:04:05+00:00', 'O''Brien')`,
+			c.Statements[0].Interpolated(),
+		)
+	}
+
+	// Every placeholder style is interpolated, quoted text is left alone
+	for _, s := range []Statement{
+		{Query: `UPDATE t SET a = ?, b = ? WHERE c = '?'`, Args: []interface{}{nil, true}},
+		{Query: `UPDATE t SET a = @p1, b = @p2 WHERE c = '?'`, Args: []interface{}{nil, true}},
+		{Query: `UPDATE t SET a = :1, b = :2 WHERE c = '?'`, Args: []interface{}{nil, true}},
+		{Query: `UPDATE t SET a = :a, b = @b WHERE c = '?'`, Args: []interface{}{sql.Named("b", true), sql.Named("a", nil)}},
+	} {
+		assert.Equal(t, `UPDATE t SET a = NULL, b = TRUE WHERE c = '?'`, s.Interpolated(), s.Query)
+	}
+	s := Statement{Query: `SELECT $1::text, $2, $3`, Args: []interface{}{2.5, []byte("hi"), "x"}}
+	assert.Equal(t, `SELECT 2.5::text, X'6869', 'x'`, s.Interpolated())
+}
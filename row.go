@@ -1,44 +1,296 @@
 package fixtures
 
 import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v2"
 )
 
 const (
-	onInsertNow    = "ON_INSERT_NOW()"
-	onUpdateNow    = "ON_UPDATE_NOW()"
-	postgresDriver = "postgres"
+	onInsertNow     = "ON_INSERT_NOW()"
+	onUpdateNow     = "ON_UPDATE_NOW()"
+	onInsertUUID    = "ON_INSERT_UUID()"
+	setNull         = "NULL()"
+	varPrefix       = "VAR("
+	exprPrefix      = "EXPR("
+	bytesPrefix     = "BYTES("
+	commentTable    = "#"
+	postgresDriver  = "postgres"
+	mysqlDriver     = "mysql"
+	sqliteDriver    = "sqlite"
+	sqlite3Driver   = "sqlite3"
+	sqlserverDriver = "sqlserver"
+	mssqlDriver     = "mssql"
+	oracleDriver    = "oracle"
+	godrorDriver    = "godror"
 )
 
+// nowValue marks a column set to the current time, it is resolved when the
+// query values are requested rather than when the row is initialised
+type nowValue struct{}
+
+// varValue marks a column set to a variable of the Context, it is resolved
+// when the query values are requested
+type varValue struct {
+	name string
+}
+
+// parseVar returns the variable a VAR(name) value refers to
+func parseVar(value interface{}) (varValue, bool) {
+	sv, ok := value.(string)
+	if !ok || !strings.HasPrefix(sv, varPrefix) || !strings.HasSuffix(sv, ")") {
+		return varValue{}, false
+	}
+	return varValue{name: strings.TrimSpace(sv[len(varPrefix) : len(sv)-1])}, true
+}
+
+// exprValue marks a column set to a raw SQL expression, which is written
+// into the query as is instead of being bound to a placeholder
+type exprValue struct {
+	sql string
+}
+
+// parseExpr returns the SQL expression of an EXPR(sql) value
+func parseExpr(value interface{}) (exprValue, bool) {
+	sv, ok := value.(string)
+	if !ok || !strings.HasPrefix(sv, exprPrefix) || !strings.HasSuffix(sv, ")") {
+		return exprValue{}, false
+	}
+	return exprValue{sql: sv[len(exprPrefix) : len(sv)-1]}, true
+}
+
+// parseBytes returns the bytes a BYTES(base64) value decodes to, values
+// which are not valid base64 are not BYTES() markers
+func parseBytes(value interface{}) ([]byte, bool) {
+	sv, ok := value.(string)
+	if !ok || !strings.HasPrefix(sv, bytesPrefix) || !strings.HasSuffix(sv, ")") {
+		return nil, false
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sv[len(bytesPrefix) : len(sv)-1]))
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// validateMarker reports a string which looks like a marker but is not one,
+// such as VAR(tenant missing its closing parenthesis
+func validateMarker(value interface{}) error {
+	sv, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	for _, marker := range []string{onInsertNow, onUpdateNow, onInsertUUID, setNull} {
+		if strings.HasPrefix(sv, strings.TrimSuffix(marker, ")")) && sv != marker {
+			return fmt.Errorf("malformed marker %s, expected %s", sv, marker)
+		}
+	}
+	if strings.HasPrefix(sv, varPrefix) {
+		if v, ok := parseVar(sv); !ok || v.name == "" {
+			return fmt.Errorf("malformed marker %s, expected VAR(name)", sv)
+		}
+	}
+	if strings.HasPrefix(sv, exprPrefix) {
+		v, ok := parseExpr(sv)
+		if !ok || strings.TrimSpace(v.sql) == "" || !balancedParentheses(v.sql) {
+			return fmt.Errorf("malformed marker %s, expected EXPR(sql)", sv)
+		}
+	}
+	if strings.HasPrefix(sv, bytesPrefix) {
+		if _, ok := parseBytes(sv); !ok {
+			return fmt.Errorf("malformed marker %s, expected BYTES(base64)", sv)
+		}
+	}
+	return nil
+}
+
+// balancedParentheses reports whether every parenthesis of the SQL
+// expression is closed, parentheses within string literals are ignored
+func balancedParentheses(sql string) bool {
+	depth := 0
+	quoted := false
+	for _, r := range sql {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// Field is a column of a row along with its value
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// OrderedFields are the fields of a row written in the order they are
+// declared rather than sorted by name, fixtures set them with a mapping
+type OrderedFields []Field
+
+// UnmarshalYAML decodes a YAML mapping keeping the order of its keys
+func (fields *OrderedFields) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var items yaml.MapSlice
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+	*fields = make(OrderedFields, len(items))
+	for i, item := range items {
+		(*fields)[i] = Field{Name: fmt.Sprint(item.Key), Value: item.Value}
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a JSON object keeping the order of its keys, numbers
+// are decoded the same way as in JSON fixtures
+func (fields *OrderedFields) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token != json.Delim('{') {
+		return fmt.Errorf("ordered_fields must be an object")
+	}
+	*fields = make(OrderedFields, 0)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		*fields = append(*fields, Field{Name: token.(string), Value: normaliseJSONNumber(value)})
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+// sortedFields returns the columns of values sorted by name
+func sortedFields(values map[string]interface{}) []Field {
+	fields := make([]Field, 0, len(values))
+	for name, value := range values {
+		fields = append(fields, Field{Name: name, Value: value})
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+	return fields
+}
+
+// fields returns the ordered fields of the row followed by its other fields
+// sorted by name
+func (row *Row) fields() []Field {
+	fields := make([]Field, 0, len(row.OrderedFields)+len(row.Fields))
+	fields = append(fields, row.OrderedFields...)
+	return append(fields, sortedFields(row.Fields)...)
+}
+
+// field returns the value of a field of the row, ordered or not
+func (row *Row) field(column string) (interface{}, bool) {
+	if value, ok := row.Fields[column]; ok {
+		return value, true
+	}
+	for _, field := range row.OrderedFields {
+		if field.Name == column {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// escapedMarker returns the literal string of a value escaping a marker
+// with a leading backslash, such as \ON_INSERT_NOW() for the string
+// ON_INSERT_NOW(). Each leading backslash escapes the rest of the value so
+// \\NULL() stands for \NULL()
+func escapedMarker(value interface{}) (string, bool) {
+	sv, ok := value.(string)
+	if !ok || !strings.HasPrefix(sv, `\`) || !looksLikeMarker(sv[1:]) {
+		return "", false
+	}
+	return sv[1:], true
+}
+
+// looksLikeMarker reports whether s is a marker, a malformed one or an
+// escaped one
+func looksLikeMarker(s string) bool {
+	if isMarker(s) || validateMarker(s) != nil {
+		return true
+	}
+	return strings.HasPrefix(s, `\`) && looksLikeMarker(s[1:])
+}
+
 // Row represents a single database row
 type Row struct {
-	Table              string
-	PK                 map[string]interface{}
-	Fields             map[string]interface{}
-	insertColumnLength int
-	updateColumnLength int
-	pkColumns          []string
-	pkValues           []interface{}
-	insertColumns      []string
-	updateColumns      []string
-	insertValues       []interface{}
-	updateValues       []interface{}
+	Table               string
+	PK                  map[string]interface{}
+	Fields              map[string]interface{}
+	Delete              bool
+	OrderedFields       OrderedFields `yaml:"ordered_fields" json:"ordered_fields"`
+	MatchOn             []string      `yaml:"match_on" json:"match_on"`
+	ConflictColumns     []string      `yaml:"conflict_columns" json:"conflict_columns"`
+	UpdateColumns       []string      `yaml:"update_columns" json:"update_columns"`
+	Types               map[string]string
+	Returning           []string
+	When                map[string]interface{}
+	OverrideSystemValue bool `yaml:"override_system_value" json:"override_system_value"`
+	insertColumnLength  int
+	updateColumnLength  int
+	pkColumns           []string
+	pkValues            []interface{}
+	matchColumns        []string
+	matchValues         []interface{}
+	insertColumns       []string
+	updateColumns       []string
+	insertValues        []interface{}
+	updateValues        []interface{}
+	newUUID             func() string
+	now                 func() time.Time
+	quote               func(identifier string) string
+	dialect             Dialect
+	vars                map[string]interface{}
+	parseTimestamps     bool
+	complexDriver       string
+	columnMap           map[string]string
+	returned            map[string]interface{}
+
+	// Columns quoted for quotedDriver, computed once per driver
+	quotedDriver        string
+	quotedInsertColumns []string
+	quotedUpdateColumns []string
+	quotedPKColumns     []string
+	quotedMatchColumns  []string
 }
 
 // Init loads internal struct variables
 func (row *Row) Init() {
 	// Initial values
-	row.insertColumnLength = len(row.PK) + len(row.Fields)
-	row.updateColumnLength = len(row.PK) + len(row.Fields)
+	row.insertColumnLength = len(row.PK) + len(row.Fields) + len(row.OrderedFields)
+	row.updateColumnLength = len(row.PK) + len(row.Fields) + len(row.OrderedFields)
 	row.pkColumns = make([]string, 0)
 	row.pkValues = make([]interface{}, 0)
 	row.insertColumns = make([]string, 0)
 	row.updateColumns = make([]string, 0)
 	row.insertValues = make([]interface{}, 0)
 	row.updateValues = make([]interface{}, 0)
+	row.quotedDriver = ""
 
 	// Get and sort map keys
 	var i int
@@ -49,44 +301,369 @@ func (row *Row) Init() {
 		i++
 	}
 	sort.Strings(pkKeys)
-	fieldKeys := make([]string, len(row.Fields))
-	i = 0
-	for fieldKey := range row.Fields {
-		fieldKeys[i] = fieldKey
-		i++
-	}
-	sort.Strings(fieldKeys)
 
 	// Primary keys
 	for _, pkKey := range pkKeys {
-		row.pkColumns = append(row.pkColumns, pkKey)
-		row.pkValues = append(row.pkValues, row.PK[pkKey])
-		row.insertColumns = append(row.insertColumns, pkKey)
-		row.updateColumns = append(row.updateColumns, pkKey)
-		row.insertValues = append(row.insertValues, row.PK[pkKey])
-		row.updateValues = append(row.updateValues, row.PK[pkKey])
+		pkValue := row.PK[pkKey]
+		if literal, ok := escapedMarker(pkValue); ok {
+			pkValue = literal
+		} else if sv, ok := pkValue.(string); ok && sv == onInsertUUID {
+			pkValue = row.generateUUID()
+		} else if v, ok := parseVar(pkValue); ok {
+			pkValue = v
+		} else if b, ok := parseBytes(pkValue); ok {
+			pkValue = b
+		} else {
+			pkValue = row.typedValue(pkKey, pkValue)
+		}
+		column := row.column(pkKey)
+		row.pkColumns = append(row.pkColumns, column)
+		row.pkValues = append(row.pkValues, pkValue)
+		row.insertColumns = append(row.insertColumns, column)
+		row.insertValues = append(row.insertValues, pkValue)
+		if row.OverrideSystemValue {
+			row.updateColumnLength--
+			continue
+		}
+		row.updateColumns = append(row.updateColumns, column)
+		row.updateValues = append(row.updateValues, pkValue)
 	}
 
-	// Rest of the fields
-	for _, fieldKey := range fieldKeys {
-		sv, ok := row.Fields[fieldKey].(string)
+	// Rest of the fields, ordered fields first
+	for _, field := range row.fields() {
+		fieldKey, fieldValue := row.column(field.Name), field.Value
+		if literal, ok := escapedMarker(fieldValue); ok {
+			row.insertColumns = append(row.insertColumns, fieldKey)
+			row.updateColumns = append(row.updateColumns, fieldKey)
+			row.insertValues = append(row.insertValues, literal)
+			row.updateValues = append(row.updateValues, literal)
+			continue
+		}
+		sv, ok := fieldValue.(string)
+		if ok && sv == onInsertUUID {
+			row.insertColumns = append(row.insertColumns, fieldKey)
+			row.insertValues = append(row.insertValues, row.generateUUID())
+			row.updateColumnLength--
+			continue
+		}
 		if ok && sv == onInsertNow {
 			row.insertColumns = append(row.insertColumns, fieldKey)
-			row.insertValues = append(row.insertValues, time.Now())
+			row.insertValues = append(row.insertValues, nowValue{})
 			row.updateColumnLength--
 			continue
 		}
 		if ok && sv == onUpdateNow {
 			row.updateColumns = append(row.updateColumns, fieldKey)
-			row.updateValues = append(row.updateValues, time.Now())
+			row.updateValues = append(row.updateValues, nowValue{})
 			row.insertColumnLength--
 			continue
 		}
+		if ok && sv == setNull {
+			row.insertColumns = append(row.insertColumns, fieldKey)
+			row.updateColumns = append(row.updateColumns, fieldKey)
+			row.insertValues = append(row.insertValues, nil)
+			row.updateValues = append(row.updateValues, nil)
+			continue
+		}
+		if v, ok := parseVar(fieldValue); ok {
+			row.insertColumns = append(row.insertColumns, fieldKey)
+			row.updateColumns = append(row.updateColumns, fieldKey)
+			row.insertValues = append(row.insertValues, v)
+			row.updateValues = append(row.updateValues, v)
+			continue
+		}
+		if v, ok := parseExpr(fieldValue); ok {
+			row.insertColumns = append(row.insertColumns, fieldKey)
+			row.updateColumns = append(row.updateColumns, fieldKey)
+			row.insertValues = append(row.insertValues, v)
+			row.updateValues = append(row.updateValues, v)
+			continue
+		}
+		if b, ok := parseBytes(fieldValue); ok {
+			row.insertColumns = append(row.insertColumns, fieldKey)
+			row.updateColumns = append(row.updateColumns, fieldKey)
+			row.insertValues = append(row.insertValues, b)
+			row.updateValues = append(row.updateValues, b)
+			continue
+		}
+		value := row.typedValue(field.Name, fieldValue)
 		row.insertColumns = append(row.insertColumns, fieldKey)
 		row.updateColumns = append(row.updateColumns, fieldKey)
-		row.insertValues = append(row.insertValues, row.Fields[fieldKey])
-		row.updateValues = append(row.updateValues, row.Fields[fieldKey])
+		row.insertValues = append(row.insertValues, value)
+		row.updateValues = append(row.updateValues, value)
+	}
+
+	// Existing rows only get their update_columns written, when listed
+	if len(row.UpdateColumns) > 0 {
+		listed := make(map[string]bool, len(row.UpdateColumns))
+		for _, column := range row.UpdateColumns {
+			listed[row.column(column)] = true
+		}
+		columns := make([]string, 0, len(row.UpdateColumns))
+		values := make([]interface{}, 0, len(row.UpdateColumns))
+		for k, column := range row.updateColumns {
+			if listed[column] {
+				columns = append(columns, column)
+				values = append(values, row.updateValues[k])
+			}
+		}
+		row.updateColumns = columns
+		row.updateValues = values
+		row.updateColumnLength = len(columns)
+	}
+
+	// Columns identifying an existing row, the primary key unless the row
+	// is matched on other columns
+	row.matchColumns = row.pkColumns
+	row.matchValues = row.pkValues
+	if len(row.MatchOn) > 0 {
+		row.matchColumns = make([]string, 0)
+		row.matchValues = make([]interface{}, 0)
+		for _, column := range row.MatchOn {
+			value, ok := row.PK[column]
+			if !ok {
+				value, _ = row.field(column)
+			}
+			if literal, ok := escapedMarker(value); ok {
+				value = literal
+			} else if v, ok := parseVar(value); ok {
+				value = v
+			} else if b, ok := parseBytes(value); ok {
+				value = b
+			} else {
+				value = row.typedValue(column, value)
+			}
+			row.matchColumns = append(row.matchColumns, row.column(column))
+			row.matchValues = append(row.matchValues, value)
+		}
+	}
+}
+
+// column returns the database column a fixture key stands for, see
+// Context.ColumnMap. Unmapped keys are the column name
+func (row *Row) column(key string) string {
+	if column, ok := row.columnMap[key]; ok {
+		return column
+	}
+	return key
+}
+
+// columns returns the database columns the fixture keys stand for
+func (row *Row) columns(keys []string) []string {
+	columns := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = row.column(key)
+	}
+	return columns
+}
+
+// typedValue returns value coerced to the type given to column in types,
+// markers are returned as is and so are values of untyped columns unless
+// they are timestamps to parse or lists and maps to serialize
+func (row *Row) typedValue(column string, value interface{}) interface{} {
+	if row.complexDriver != "" {
+		value = serializeComplex(row.complexDriver, value)
+	}
+	typ, ok := row.Types[column]
+	if !ok && row.parseTimestamps {
+		return parseTimestamp(value)
+	}
+	if !ok || isMarker(value) {
+		return value
+	}
+	if _, ok := value.(varValue); ok {
+		return value
+	}
+	coerced, err := coerceValue(value, typ)
+	if err != nil {
+		return value
+	}
+	return coerced
+}
+
+// comment reports whether the row only annotates the fixture, its table is
+// the # sentinel and whatever else it holds is ignored. Comment rows are
+// skipped
+func (row *Row) comment() bool {
+	return row.Table == commentTable
+}
+
+// empty reports whether nothing at all is set on the row, such as a null
+// entry of a YAML list. Empty rows are skipped
+func (row *Row) empty() bool {
+	return row.Table == "" && len(row.PK) == 0 && len(row.Fields) == 0 &&
+		len(row.OrderedFields) == 0 &&
+		!row.Delete && len(row.MatchOn) == 0 && len(row.ConflictColumns) == 0 &&
+		len(row.UpdateColumns) == 0 && len(row.Types) == 0 &&
+		len(row.Returning) == 0 && len(row.When) == 0
+}
+
+// guarded reports whether the row is to be skipped as one of the variables
+// of its when guard does not hold the expected value. Values are compared by
+// their string form so 1 matches both int and int64 variables
+func (row *Row) guarded(vars map[string]interface{}) bool {
+	for name, expected := range row.When {
+		if fmt.Sprint(vars[name]) != fmt.Sprint(expected) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the row can be turned into queries, it needs a table and
+// some columns, markers have to be well formed rather than silently written
+// as strings, a column set in both pk and fields would be written twice,
+// match_on, conflict_columns and update_columns columns need a value, rows
+// are only looked up by bound values, never by EXPR() expressions, and typed
+// columns need a value of their type
+func (row *Row) Validate() error {
+	if row.Table == "" {
+		return fmt.Errorf("table is not set")
+	}
+	if len(row.PK) == 0 && len(row.Fields) == 0 && len(row.OrderedFields) == 0 {
+		return fmt.Errorf("neither pk nor fields are set")
+	}
+	for _, field := range append(sortedFields(row.PK), row.fields()...) {
+		if err := validateMarker(field.Value); err != nil {
+			return fmt.Errorf("column %s of table %s: %v", field.Name, row.Table, err)
+		}
+	}
+
+	typedColumns := make([]string, 0, len(row.Types))
+	for column := range row.Types {
+		typedColumns = append(typedColumns, column)
+	}
+	sort.Strings(typedColumns)
+	for _, column := range typedColumns {
+		if !knownType(row.Types[column]) {
+			return fmt.Errorf("unknown type %s of column %s of table %s", row.Types[column], column, row.Table)
+		}
+		value, ok := row.PK[column]
+		if !ok {
+			value, _ = row.field(column)
+		}
+		if isMarker(value) {
+			continue
+		}
+		if _, err := coerceValue(value, row.Types[column]); err != nil {
+			return fmt.Errorf("column %s of table %s: %v", column, row.Table, err)
+		}
+	}
+
+	for _, column := range row.MatchOn {
+		_, inPK := row.PK[column]
+		_, inFields := row.field(column)
+		if !inPK && !inFields {
+			return fmt.Errorf("match_on column %s of table %s is not set", column, row.Table)
+		}
+		value, _ := row.field(column)
+		if _, ok := parseExpr(value); ok {
+			return fmt.Errorf("match_on column %s of table %s is set to an EXPR()", column, row.Table)
+		}
+		// Rows are only looked up by values known before the query
+		if pkValue, ok := row.PK[column]; ok {
+			value = pkValue
+		}
+		switch value {
+		case setNull, onInsertUUID, onInsertNow, onUpdateNow:
+			return fmt.Errorf("match_on column %s of table %s is set to %s", column, row.Table, value)
+		}
+	}
+	for _, column := range row.ConflictColumns {
+		_, inPK := row.PK[column]
+		_, inFields := row.field(column)
+		if !inPK && !inFields {
+			return fmt.Errorf("conflict_columns column %s of table %s is not set", column, row.Table)
+		}
+	}
+	for _, column := range row.UpdateColumns {
+		_, inPK := row.PK[column]
+		_, inFields := row.field(column)
+		if !inPK && !inFields {
+			return fmt.Errorf("update_columns column %s of table %s is not set", column, row.Table)
+		}
+	}
+	pkColumns := make([]string, 0)
+	for column, value := range row.PK {
+		if _, ok := parseExpr(value); ok {
+			pkColumns = append(pkColumns, column)
+		}
+	}
+	if len(pkColumns) > 0 {
+		sort.Strings(pkColumns)
+		return fmt.Errorf("pk column %s of table %s is set to an EXPR()", pkColumns[0], row.Table)
+	}
+
+	columns := make([]string, 0)
+	for _, field := range row.fields() {
+		if _, ok := row.PK[field.Name]; ok {
+			columns = append(columns, field.Name)
+		}
+	}
+	if len(columns) > 0 {
+		sort.Strings(columns)
+		return fmt.Errorf("column %s of table %s is set in both pk and fields", columns[0], row.Table)
+	}
+	for _, field := range row.OrderedFields {
+		if _, ok := row.Fields[field.Name]; ok {
+			return fmt.Errorf("column %s of table %s is set in both fields and ordered_fields", field.Name, row.Table)
+		}
+	}
+	seen := make(map[string]bool, len(row.OrderedFields))
+	for _, field := range row.OrderedFields {
+		if seen[field.Name] {
+			return fmt.Errorf("column %s of table %s is set twice in ordered_fields", field.Name, row.Table)
+		}
+		seen[field.Name] = true
+	}
+	return nil
+}
+
+// validateVars checks every VAR(name) value and when guard of the row refers
+// to one of vars
+func (row *Row) validateVars(vars map[string]interface{}) error {
+	for _, field := range sortedFields(row.When) {
+		if _, ok := vars[field.Name]; !ok {
+			return fmt.Errorf("unknown variable %s used by when", field.Name)
+		}
+	}
+	for _, field := range append(sortedFields(row.PK), row.fields()...) {
+		v, ok := parseVar(field.Value)
+		if !ok {
+			continue
+		}
+		if _, ok := vars[v.name]; !ok {
+			return fmt.Errorf("unknown variable %s used by column %s", v.name, field.Name)
+		}
+	}
+	return nil
+}
+
+// resolveTable returns the table name of the row with its {name} variables
+// replaced by their values in vars, such as users_{tenant}
+func (row *Row) resolveTable(vars map[string]interface{}) (string, error) {
+	var resolved strings.Builder
+	table := row.Table
+	for {
+		start := strings.IndexByte(table, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(table[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable in table name")
+		}
+		name := strings.TrimSpace(table[start+1 : start+end])
+		value, ok := vars[name]
+		if !ok {
+			return "", fmt.Errorf("unknown variable %s used by table name", name)
+		}
+		resolved.WriteString(table[:start])
+		resolved.WriteString(fmt.Sprint(value))
+		table = table[start+end+1:]
 	}
+	resolved.WriteString(table)
+	return resolved.String(), nil
 }
 
 // GetInsertColumnsLength returns number of columns for INSERT query
@@ -100,75 +677,270 @@ func (row *Row) GetUpdateColumnsLength() int {
 }
 
 // GetInsertColumns returns a slice of column names for INSERT query
-func (row *Row) GetInsertColumns() []string {
-	escapedColumns := make([]string, len(row.insertColumns))
-	for i, insertColumn := range row.insertColumns {
-		escapedColumns[i] = fmt.Sprintf("\"%s\"", insertColumn)
-	}
-	return escapedColumns
+func (row *Row) GetInsertColumns(driver string) []string {
+	row.quoteColumns(driver)
+	return row.quotedInsertColumns
 }
 
 // GetUpdateColumns returns a slice of column names for UPDATE query
-func (row *Row) GetUpdateColumns() []string {
-	escapedColumns := make([]string, len(row.updateColumns))
-	for i, updateColumn := range row.updateColumns {
-		escapedColumns[i] = fmt.Sprintf("\"%s\"", updateColumn)
-	}
-	return escapedColumns
+func (row *Row) GetUpdateColumns(driver string) []string {
+	row.quoteColumns(driver)
+	return row.quotedUpdateColumns
 }
 
 // GetInsertValues returns a slice of values for INSERT query
 func (row *Row) GetInsertValues() []interface{} {
-	return row.insertValues
+	return row.resolveValues(row.insertColumns, row.insertValues)
 }
 
 // GetUpdateValues returns a slice of values for UPDATE query
 func (row *Row) GetUpdateValues() []interface{} {
-	return row.updateValues
+	return row.resolveValues(row.updateColumns, row.updateValues)
 }
 
 // GetInsertPlaceholders returns a slice of placeholders for INSERT query
 func (row *Row) GetInsertPlaceholders(driver string) []string {
+	return row.GetInsertPlaceholdersFrom(driver, 1)
+}
+
+// GetInsertPlaceholdersFrom returns a slice of placeholders for INSERT query
+// numbered from start, for queries binding other values before the row's
+func (row *Row) GetInsertPlaceholdersFrom(driver string, start int) []string {
 	placeholders := make([]string, row.GetInsertColumnsLength())
-	for i := 0; i < row.GetInsertColumnsLength(); i++ {
-		if driver == postgresDriver {
-			placeholders[i] = fmt.Sprintf("$%d", i+1)
-		} else {
-			placeholders[i] = "?"
+	n := start
+	for j, value := range row.insertValues {
+		if expr, ok := value.(exprValue); ok {
+			placeholders[j] = expr.sql
+			continue
 		}
+		placeholders[j] = row.placeholder(driver, n, row.insertColumns[j])
+		n++
 	}
 	return placeholders
 }
 
 // GetUpdatePlaceholders returns a slice of placeholders for UPDATE query
 func (row *Row) GetUpdatePlaceholders(driver string) []string {
+	return row.updatePlaceholders(driver, 0)
+}
+
+// updatePlaceholders returns a slice of placeholders for UPDATE query
+// numbered after the first i values of the query
+func (row *Row) updatePlaceholders(driver string, i int) []string {
 	placeholders := make([]string, row.GetUpdateColumnsLength())
-	for i, c := range row.GetUpdateColumns() {
-		if driver == postgresDriver {
-			placeholders[i] = fmt.Sprintf("%s = $%d", c, i+1)
-		} else {
-			placeholders[i] = fmt.Sprintf("%s = ?", c)
+	for j, c := range row.GetUpdateColumns(driver) {
+		if expr, ok := row.updateValues[j].(exprValue); ok {
+			placeholders[j] = fmt.Sprintf("%s = %s", c, expr.sql)
+			continue
 		}
+		i++
+		placeholders[j] = fmt.Sprintf("%s = %s", c, row.placeholder(driver, i, row.updateColumns[j]))
 	}
 	return placeholders
 }
 
 // GetWhere returns a where condition based on primary key with placeholders
 func (row *Row) GetWhere(driver string, i int) string {
-	wheres := make([]string, len(row.PK))
-	j := i
-	for _, c := range row.pkColumns {
-		if driver == postgresDriver {
-			wheres[i-j] = fmt.Sprintf("%s = $%d", c, i+1)
-		} else {
-			wheres[i-j] = fmt.Sprintf("%s = ?", c)
-		}
-		i++
+	return row.whereClause(driver, row.GetPKColumns(driver), row.pkColumns, i)
+}
+
+// GetMatchWhere returns a where condition based on the columns identifying
+// an existing row with placeholders, see GetMatchValues
+func (row *Row) GetMatchWhere(driver string, i int) string {
+	return row.whereClause(driver, row.GetMatchColumns(driver), row.matchColumns, i)
+}
+
+// GetMatchColumns returns a slice of the column names identifying an existing
+// row, the match_on columns or the primary key columns
+func (row *Row) GetMatchColumns(driver string) []string {
+	row.quoteColumns(driver)
+	return row.quotedMatchColumns
+}
+
+// GetMatchValues returns a slice of the values identifying an existing row,
+// the match_on column values or the primary key values
+func (row *Row) GetMatchValues() []interface{} {
+	return row.resolveValues(row.matchColumns, row.matchValues)
+}
+
+// GetConflictColumns returns a slice of the columns upserts conflict on,
+// the conflict_columns or else the columns identifying an existing row
+func (row *Row) GetConflictColumns(driver string) []string {
+	if len(row.ConflictColumns) == 0 {
+		return row.GetMatchColumns(driver)
+	}
+	return quoteIdentifiers(row.quoter(driver), row.columns(row.ConflictColumns))
+}
+
+// whereClause returns a where condition comparing the quoted columns to
+// placeholders numbered after the first i values of the query, or named after
+// the columns names
+func (row *Row) whereClause(driver string, columns, names []string, i int) string {
+	wheres := make([]string, len(columns))
+	for k, c := range columns {
+		wheres[k] = fmt.Sprintf("%s = %s", c, row.placeholder(driver, i+k+1, names[k]))
 	}
 	return strings.Join(wheres, " AND ")
 }
 
+// GetPKColumns returns a slice of primary key column names
+func (row *Row) GetPKColumns(driver string) []string {
+	row.quoteColumns(driver)
+	return row.quotedPKColumns
+}
+
+// quoteColumns escapes the column names for driver, unless they already are
+func (row *Row) quoteColumns(driver string) {
+	if row.quotedDriver == driver && row.quotedInsertColumns != nil {
+		return
+	}
+	quote := row.quoter(driver)
+	row.quotedDriver = driver
+	row.quotedInsertColumns = quoteIdentifiers(quote, row.insertColumns)
+	row.quotedUpdateColumns = quoteIdentifiers(quote, row.updateColumns)
+	row.quotedPKColumns = quoteIdentifiers(quote, row.pkColumns)
+	row.quotedMatchColumns = quoteIdentifiers(quote, row.matchColumns)
+}
+
+// quoter returns the function escaping the identifiers of row for driver
+func (row *Row) quoter(driver string) func(string) string {
+	if row.quote != nil {
+		return row.quote
+	}
+	if row.dialect != nil {
+		return row.dialect.Quote
+	}
+	return driverQuoter(driver)
+}
+
+// placeholder returns the n-th (1-based) placeholder of a query of row, the
+// value of column, which a NamedDialect names after the column
+func (row *Row) placeholder(driver string, n int, column string) string {
+	if dialect, ok := row.dialect.(NamedDialect); ok {
+		return dialect.NamedPlaceholder(column)
+	}
+	if row.dialect != nil {
+		return row.dialect.Placeholder(n)
+	}
+	return placeholder(driver, n)
+}
+
+// named reports whether the values of row are bound by name, see NamedDialect
+func (row *Row) named() bool {
+	_, ok := row.dialect.(NamedDialect)
+	return ok
+}
+
+// Returned returns the values of the Returning columns read back when the
+// row was last inserted or updated, such as database defaults
+func (row *Row) Returned() map[string]interface{} {
+	return row.returned
+}
+
 // GetPKValues returns a slice of primary key values
 func (row *Row) GetPKValues() []interface{} {
-	return row.pkValues
+	return row.resolveValues(row.pkColumns, row.pkValues)
+}
+
+// quoteIdentifiers escapes every identifier with quote
+func quoteIdentifiers(quote func(string) string, identifiers []string) []string {
+	quoted := make([]string, len(identifiers))
+	for i, identifier := range identifiers {
+		quoted[i] = quote(identifier)
+	}
+	return quoted
+}
+
+// generateUUID returns a fresh UUID for an ON_INSERT_UUID() value
+func (row *Row) generateUUID() string {
+	if row.newUUID != nil {
+		return row.newUUID()
+	}
+	return newUUID()
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// quoteTable escapes a table name with quote, each segment of a schema
+// qualified name such as billing.invoices is quoted separately
+func quoteTable(quote func(string) string, table string) string {
+	segments := strings.Split(table, ".")
+	return strings.Join(quoteIdentifiers(quote, segments), ".")
+}
+
+// quoteTables escapes every table name with quote
+func quoteTables(quote func(string) string, tables []string) []string {
+	quoted := make([]string, len(tables))
+	for i, table := range tables {
+		quoted[i] = quoteTable(quote, table)
+	}
+	return quoted
+}
+
+// driverQuoter returns the function escaping identifiers for the given
+// driver, MySQL uses backticks, SQL Server square brackets while postgres,
+// sqlite and sqlite3 use ANSI double quotes
+func driverQuoter(driver string) func(string) string {
+	return dialectFor(driver).Quote
+}
+
+// unquotedIdentifier returns identifier as is, see
+// Context.UnquotedIdentifiers
+func unquotedIdentifier(identifier string) string {
+	return identifier
+}
+
+// resolveValues returns a copy of the values of columns with markers replaced
+// by the values they stand for at the time of the call, EXPR() values are
+// left out as they are not bound to placeholders. Values bound by name are
+// returned as sql.NamedArg values named after their column
+func (row *Row) resolveValues(columns []string, values []interface{}) []interface{} {
+	var now time.Time
+	if row.now != nil {
+		now = row.now()
+	} else {
+		now = time.Now()
+	}
+	named := row.named()
+	resolved := make([]interface{}, 0, len(values))
+	for k, value := range values {
+		switch v := value.(type) {
+		case nowValue:
+			value = now
+		case varValue:
+			value = row.vars[v.name]
+		case exprValue:
+			continue
+		}
+		if named {
+			value = sql.Named(columns[k], value)
+		}
+		resolved = append(resolved, value)
+	}
+	return resolved
+}
+
+// uniqueArgs returns values without the values bound by a name already bound
+// earlier, a query binding the same column twice, such as an UPDATE setting
+// the column its where condition compares, binds its value once by name
+func uniqueArgs(values []interface{}) []interface{} {
+	unique := make([]interface{}, 0, len(values))
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if arg, ok := value.(sql.NamedArg); ok {
+			if seen[arg.Name] {
+				continue
+			}
+			seen[arg.Name] = true
+		}
+		unique = append(unique, value)
+	}
+	return unique
 }
@@ -1,7 +1,12 @@
 package fixtures
 
 import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -38,14 +43,32 @@ func TestRow(t *testing.T) {
 	// Test insert and update columns
 	expectedStrings = []string{"\"other_id\"", "\"some_id\"",
 		"\"boolean_field\"", "\"created_at\"", "\"string_field\""}
-	assert.Equal(t, expectedStrings, row.GetInsertColumns())
+	assert.Equal(t, expectedStrings, row.GetInsertColumns("postgres"))
 	expectedStrings = []string{"\"other_id\"", "\"some_id\"",
 		"\"boolean_field\"", "\"string_field\"", "\"updated_at\""}
-	assert.Equal(t, expectedStrings, row.GetUpdateColumns())
+	assert.Equal(t, expectedStrings, row.GetUpdateColumns("postgres"))
+
+	// Test MySQL columns are quoted with backticks
+	expectedStrings = []string{"`other_id`", "`some_id`",
+		"`boolean_field`", "`created_at`", "`string_field`"}
+	assert.Equal(t, expectedStrings, row.GetInsertColumns("mysql"))
+	expectedStrings = []string{"`other_id`", "`some_id`",
+		"`boolean_field`", "`string_field`", "`updated_at`"}
+	assert.Equal(t, expectedStrings, row.GetUpdateColumns("mysql"))
 
 	// Test postgres placeholders ($1, $2 and so on)
 	expectedStrings = []string{"$1", "$2", "$3", "$4", "$5"}
 	assert.Equal(t, expectedStrings, row.GetInsertPlaceholders("postgres"))
+	assert.Equal(t, expectedStrings, row.GetInsertPlaceholdersFrom("postgres", 1))
+
+	// Test placeholders numbered after preceding values
+	expectedStrings = []string{"$4", "$5", "$6", "$7", "$8"}
+	assert.Equal(t, expectedStrings, row.GetInsertPlaceholdersFrom("postgres", 4))
+	expectedStrings = []string{"@p4", "@p5", "@p6", "@p7", "@p8"}
+	assert.Equal(t, expectedStrings, row.GetInsertPlaceholdersFrom("sqlserver", 4))
+	expectedStrings = []string{"?", "?", "?", "?", "?"}
+	assert.Equal(t, expectedStrings, row.GetInsertPlaceholdersFrom("mysql", 4))
+
 	expectedStrings = []string{"\"other_id\" = $1", "\"some_id\" = $2",
 		"\"boolean_field\" = $3", "\"string_field\" = $4", "\"updated_at\" = $5"}
 	assert.Equal(t, expectedStrings, row.GetUpdatePlaceholders("postgres"))
@@ -56,12 +79,592 @@ func TestRow(t *testing.T) {
 	expectedStrings = []string{"\"other_id\" = ?", "\"some_id\" = ?",
 		"\"boolean_field\" = ?", "\"string_field\" = ?", "\"updated_at\" = ?"}
 	assert.Equal(t, expectedStrings, row.GetUpdatePlaceholders("sqlite"))
+	expectedStrings = []string{"`other_id` = ?", "`some_id` = ?",
+		"`boolean_field` = ?", "`string_field` = ?", "`updated_at` = ?"}
+	assert.Equal(t, expectedStrings, row.GetUpdatePlaceholders("mysql"))
+
+	// Test both sqlite driver names quote like postgres
+	for _, driver := range []string{sqliteDriver, sqlite3Driver} {
+		expectedStrings = []string{"\"other_id\"", "\"some_id\"",
+			"\"boolean_field\"", "\"created_at\"", "\"string_field\""}
+		assert.Equal(t, expectedStrings, row.GetInsertColumns(driver))
+		expectedStrings = []string{"?", "?", "?", "?", "?"}
+		assert.Equal(t, expectedStrings, row.GetInsertPlaceholders(driver))
+	}
 
 	// Test where clause
-	expectedString = "other_id = $3 AND some_id = $4"
+	expectedString = "\"other_id\" = $3 AND \"some_id\" = $4"
 	assert.Equal(t, expectedString, row.GetWhere("postgres", 2))
+	expectedString = "`other_id` = ? AND `some_id` = ?"
+	assert.Equal(t, expectedString, row.GetWhere("mysql", 0))
 
 	// Test primary key values
 	expectedInterfaces = []interface{}{interface{}(2), interface{}(1)}
 	assert.Equal(t, expectedInterfaces, row.GetPKValues())
 }
+
+func TestRowResolvesNowWhenValuesAreRequested(t *testing.T) {
+	row := &Row{
+		Table: "some_table",
+		PK: map[string]interface{}{
+			"id": interface{}(1),
+		},
+		Fields: map[string]interface{}{
+			"string_field": interface{}("foobar"),
+			"created_at":   interface{}("ON_INSERT_NOW()"),
+			"updated_at":   interface{}("ON_UPDATE_NOW()"),
+		},
+	}
+	row.Init()
+
+	initialisedAt := time.Now()
+	time.Sleep(time.Millisecond)
+
+	// Timestamps are taken when the values are requested, not in Init
+	insertValues := row.GetInsertValues()
+	assert.Equal(t, interface{}(1), insertValues[0])
+	assert.True(t, insertValues[1].(time.Time).After(initialisedAt))
+	assert.Equal(t, interface{}("foobar"), insertValues[2])
+	updateValues := row.GetUpdateValues()
+	assert.Equal(t, interface{}(1), updateValues[0])
+	assert.Equal(t, interface{}("foobar"), updateValues[1])
+	assert.True(t, updateValues[2].(time.Time).After(initialisedAt))
+
+	// Requesting the values again gives a fresh timestamp
+	time.Sleep(time.Millisecond)
+	assert.True(t, row.GetInsertValues()[1].(time.Time).After(insertValues[1].(time.Time)))
+}
+
+func TestRowWithNullField(t *testing.T) {
+	row := &Row{
+		Table: "some_table",
+		PK: map[string]interface{}{
+			"id": interface{}(1),
+		},
+		Fields: map[string]interface{}{
+			"string_field": interface{}("NULL()"),
+		},
+	}
+	row.Init()
+
+	// The column is kept and bound to nil for both queries
+	assert.Equal(t, []string{"\"id\"", "\"string_field\""}, row.GetInsertColumns("postgres"))
+	assert.Equal(t, []interface{}{interface{}(1), nil}, row.GetInsertValues())
+	assert.Equal(t, []string{"\"id\"", "\"string_field\""}, row.GetUpdateColumns("postgres"))
+	assert.Equal(t, []interface{}{interface{}(1), nil}, row.GetUpdateValues())
+}
+
+func TestRowWithInsertUUID(t *testing.T) {
+	var generated int
+	row := &Row{
+		Table: "some_table",
+		PK: map[string]interface{}{
+			"id": interface{}("ON_INSERT_UUID()"),
+		},
+		Fields: map[string]interface{}{
+			"external_id":  interface{}("ON_INSERT_UUID()"),
+			"string_field": interface{}("foobar"),
+		},
+		newUUID: func() string {
+			generated++
+			return fmt.Sprintf("uuid-%d", generated)
+		},
+	}
+	row.Init()
+
+	// The primary key uses the same value everywhere
+	assert.Equal(t, []interface{}{"uuid-1"}, row.GetPKValues())
+	assert.Equal(t, []interface{}{"uuid-1", "uuid-2", "foobar"}, row.GetInsertValues())
+
+	// Other fields are only set on insert
+	assert.Equal(t, []string{"\"id\"", "\"string_field\""}, row.GetUpdateColumns("postgres"))
+	assert.Equal(t, []interface{}{"uuid-1", "foobar"}, row.GetUpdateValues())
+
+	// Random UUIDs are generated by default
+	assert.Regexp(
+		t,
+		regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		newUUID(),
+	)
+	assert.NotEqual(t, newUUID(), newUUID())
+}
+
+func BenchmarkRowGetters(b *testing.B) {
+	// A wide table with 50 columns
+	row := &Row{
+		Table: "wide_table",
+		PK: map[string]interface{}{
+			"id": interface{}(1),
+		},
+		Fields: make(map[string]interface{}),
+	}
+	for i := 1; i < 50; i++ {
+		row.Fields[fmt.Sprintf("column_%d", i)] = interface{}(i)
+	}
+	row.Init()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		// The getters used to build the SELECT, INSERT and UPDATE of a row
+		row.GetWhere("postgres", 0)
+		row.GetInsertColumns("postgres")
+		row.GetInsertPlaceholders("postgres")
+		row.GetUpdatePlaceholders("postgres")
+		row.GetWhere("postgres", row.GetUpdateColumnsLength())
+	}
+}
+
+func TestRowValidate(t *testing.T) {
+	row := &Row{
+		Table:  "some_table",
+		PK:     map[string]interface{}{"id": 1},
+		Fields: map[string]interface{}{"string_field": "foobar"},
+	}
+	assert.Nil(t, row.Validate())
+
+	// A column can only be part of either the primary key or the fields
+	row.Fields["id"] = 2
+	assert.EqualError(t, row.Validate(), "column id of table some_table is set in both pk and fields")
+}
+
+func TestRowWithMatchOn(t *testing.T) {
+	row := &Row{
+		Table: "users",
+		Fields: map[string]interface{}{
+			"email": interface{}("foo@example.com"),
+			"name":  interface{}("Foo"),
+		},
+		MatchOn: []string{"email"},
+	}
+	assert.Nil(t, row.Validate())
+	row.Init()
+
+	// Existing rows are found by the match_on columns
+	assert.Equal(t, []string{"\"email\""}, row.GetMatchColumns("postgres"))
+	assert.Equal(t, "\"email\" = $3", row.GetMatchWhere("postgres", 2))
+	assert.Equal(t, "`email` = ?", row.GetMatchWhere("mysql", 0))
+	assert.Equal(t, []interface{}{"foo@example.com"}, row.GetMatchValues())
+
+	// Without match_on the primary key is used
+	row = &Row{
+		Table: "users",
+		PK:    map[string]interface{}{"id": interface{}(1)},
+	}
+	row.Init()
+	assert.Equal(t, row.GetWhere("postgres", 0), row.GetMatchWhere("postgres", 0))
+	assert.Equal(t, row.GetPKValues(), row.GetMatchValues())
+
+	// The match_on columns need a value
+	row = &Row{
+		Table:   "users",
+		Fields:  map[string]interface{}{"name": interface{}("Foo")},
+		MatchOn: []string{"email"},
+	}
+	assert.EqualError(t, row.Validate(), "match_on column email of table users is not set")
+}
+
+func TestQuoteTable(t *testing.T) {
+	// Bare names are quoted as a single identifier
+	assert.Equal(t, "\"invoices\"", quoteTable(driverQuoter("postgres"), "invoices"))
+	assert.Equal(t, "`invoices`", quoteTable(driverQuoter("mysql"), "invoices"))
+
+	// Each segment of a schema qualified name is quoted separately
+	assert.Equal(t, "\"billing\".\"invoices\"", quoteTable(driverQuoter("postgres"), "billing.invoices"))
+	assert.Equal(t, "`billing`.`invoices`", quoteTable(driverQuoter("mysql"), "billing.invoices"))
+	assert.Equal(t, []string{"\"billing\".\"invoices\"", "\"users\""}, quoteTables(driverQuoter("sqlite"), []string{"billing.invoices", "users"}))
+}
+
+func TestRowWithVars(t *testing.T) {
+	row := &Row{
+		Table: "some_table",
+		PK: map[string]interface{}{
+			"id": interface{}("VAR(id)"),
+		},
+		Fields: map[string]interface{}{
+			"string_field": interface{}("VAR( tenant )"),
+		},
+	}
+	row.vars = map[string]interface{}{"id": 7, "tenant": "acme"}
+	row.Init()
+
+	// Variables are resolved when the values are requested
+	assert.Equal(t, []interface{}{7, "acme"}, row.GetInsertValues())
+	assert.Equal(t, []interface{}{7, "acme"}, row.GetUpdateValues())
+	assert.Equal(t, []interface{}{7}, row.GetPKValues())
+
+	// Unknown variables are reported
+	assert.Nil(t, row.validateVars(row.vars))
+	assert.EqualError(t, row.validateVars(map[string]interface{}{"id": 7}), "unknown variable tenant used by column string_field")
+}
+
+func TestRowWithTypes(t *testing.T) {
+	// YAML turns an unquoted yes into true but keeps a quoted one a string
+	rows, err := parseFixture([]byte(`
+- table: 'some_table'
+  pk:
+    id: '1'
+  fields:
+    unquoted_flag: yes
+    quoted_flag: 'yes'
+    label: yes
+    ratio: 2
+    created_at: '2023-01-02 15:04:05'
+    updated_at: 'ON_UPDATE_NOW()'
+  types:
+    id: int64
+    unquoted_flag: bool
+    quoted_flag: bool
+    label: string
+    ratio: float64
+    created_at: time
+    updated_at: time
+`))
+	assert.Nil(t, err)
+	row := &rows[0]
+	assert.Nil(t, row.Validate())
+	row.Init()
+
+	// Both kinds of yes are bound as true and the marker is left alone
+	assert.Equal(
+		t,
+		[]interface{}{
+			int64(1),
+			time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC),
+			"true",
+			true,
+			float64(2),
+			true,
+		},
+		row.GetInsertValues(),
+	)
+	assert.Equal(t, []interface{}{int64(1)}, row.GetPKValues())
+	assert.IsType(t, time.Time{}, row.GetUpdateValues()[len(row.GetUpdateValues())-1])
+
+	// Values which cannot be coerced and unknown types are rejected
+	row = &Row{
+		Table:  "some_table",
+		PK:     map[string]interface{}{"id": 1},
+		Fields: map[string]interface{}{"flag": "maybe"},
+		Types:  map[string]string{"flag": "bool"},
+	}
+	assert.EqualError(t, row.Validate(), "column flag of table some_table: cannot convert maybe to bool")
+	row.Types = map[string]string{"flag": "uuid"}
+	assert.EqualError(t, row.Validate(), "unknown type uuid of column flag of table some_table")
+}
+
+func TestRowWithParseTimestamps(t *testing.T) {
+	row := &Row{
+		Table: "some_table",
+		PK:    map[string]interface{}{"id": 1},
+		Fields: map[string]interface{}{
+			"created_at": "2023-01-02T15:04:05Z",
+			"label":      "2023-01-02T15:04:05Z",
+			"code":       "20230102",
+		},
+		Types: map[string]string{"label": "string"},
+	}
+
+	// Timestamps are bound as strings by default
+	row.Init()
+	assert.Equal(
+		t,
+		[]interface{}{1, "20230102", "2023-01-02T15:04:05Z", "2023-01-02T15:04:05Z"},
+		row.GetInsertValues(),
+	)
+
+	// Typed columns keep their type
+	row.parseTimestamps = true
+	row.Init()
+	assert.Equal(
+		t,
+		[]interface{}{1, "20230102", time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC), "2023-01-02T15:04:05Z"},
+		row.GetInsertValues(),
+	)
+}
+
+func TestRowColumnsAlignWithValues(t *testing.T) {
+	columns := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "ts_created", "ts_updated"}
+	random := rand.New(rand.NewSource(1))
+
+	// unquote strips the quotes of a postgres column
+	unquote := func(column string) string {
+		return strings.Trim(column, `"`)
+	}
+	// checkAligned asserts every value belongs to the column at its index,
+	// plain values are named after their column
+	checkAligned := func(columns []string, values []interface{}) {
+		if !assert.Equal(t, len(columns), len(values)) {
+			return
+		}
+		for i, column := range columns {
+			column = unquote(column)
+			if strings.HasPrefix(column, "ts_") {
+				assert.IsType(t, time.Time{}, values[i], column)
+				continue
+			}
+			assert.Equal(t, "value of "+column, values[i], column)
+		}
+	}
+
+	for n := 0; n < 50; n++ {
+		// Fill the maps in a different order every time
+		row := &Row{
+			Table:  "some_table",
+			PK:     make(map[string]interface{}),
+			Fields: make(map[string]interface{}),
+		}
+		for _, i := range random.Perm(len(columns)) {
+			column := columns[i]
+			switch {
+			case column == "ts_created":
+				row.Fields[column] = onInsertNow
+			case column == "ts_updated":
+				row.Fields[column] = onUpdateNow
+			case i%3 == 0:
+				row.PK[column] = "value of " + column
+			default:
+				row.Fields[column] = "value of " + column
+			}
+		}
+		row.Init()
+
+		checkAligned(row.GetInsertColumns("postgres"), row.GetInsertValues())
+		checkAligned(row.GetUpdateColumns("postgres"), row.GetUpdateValues())
+		checkAligned(row.GetPKColumns("postgres"), row.GetPKValues())
+		checkAligned(row.GetMatchColumns("postgres"), row.GetMatchValues())
+	}
+}
+
+func TestRowValidateMalformedMarkers(t *testing.T) {
+	for value, expected := range map[string]string{
+		"VAR(tenant":        "malformed marker VAR(tenant, expected VAR(name)",
+		"VAR()":             "malformed marker VAR(), expected VAR(name)",
+		"EXPR(now()":        "malformed marker EXPR(now(), expected EXPR(sql)",
+		"EXPR(now()) + (1":  "malformed marker EXPR(now()) + (1, expected EXPR(sql)",
+		"EXPR( )":           "malformed marker EXPR( ), expected EXPR(sql)",
+		"ON_INSERT_NOW(":    "malformed marker ON_INSERT_NOW(, expected ON_INSERT_NOW()",
+		"ON_UPDATE_NOW( )":  "malformed marker ON_UPDATE_NOW( ), expected ON_UPDATE_NOW()",
+		"ON_INSERT_UUID()x": "malformed marker ON_INSERT_UUID()x, expected ON_INSERT_UUID()",
+		"NULL(":             "malformed marker NULL(, expected NULL()",
+		"BYTES(aGVsbG8=":    "malformed marker BYTES(aGVsbG8=, expected BYTES(base64)",
+		"BYTES(not base64)": "malformed marker BYTES(not base64), expected BYTES(base64)",
+	} {
+		row := &Row{
+			Table:  "some_table",
+			PK:     map[string]interface{}{"id": 1},
+			Fields: map[string]interface{}{"some_field": value},
+		}
+		assert.EqualError(t, row.Validate(), "column some_field of table some_table: "+expected)
+	}
+
+	// Well formed markers and ordinary strings are fine
+	row := &Row{
+		Table: "some_table",
+		PK:    map[string]interface{}{"id": "VAR(id)"},
+		Fields: map[string]interface{}{
+			"created_at": "ON_INSERT_NOW()",
+			"point":      "EXPR(point(1, 2))",
+			"label":      "EXPR(concat('(', name))",
+			"avatar":     "BYTES(aGVsbG8=)",
+			"comment":    "NULL and VAR( are fine as words",
+		},
+	}
+	assert.Nil(t, row.Validate())
+}
+
+func TestRowWithOrderedFields(t *testing.T) {
+	// Ordered fields keep the order of the fixture, before the other fields
+	rows, err := parseFixture([]byte(`
+- table: 'some_table'
+  pk:
+    id: 1
+  ordered_fields:
+    zeta: 'z'
+    alpha: 'a'
+    created_at: 'ON_INSERT_NOW()'
+  fields:
+    beta: 'b'
+`))
+	assert.Nil(t, err)
+	row := &rows[0]
+	assert.Nil(t, row.Validate())
+	row.Init()
+	assert.Equal(
+		t,
+		[]string{`"id"`, `"zeta"`, `"alpha"`, `"created_at"`, `"beta"`},
+		row.GetInsertColumns("postgres"),
+	)
+	assert.Equal(t, []interface{}{1, "z", "a"}, row.GetInsertValues()[:3])
+	assert.Equal(t, []interface{}{1, "z", "a", "b"}, row.GetUpdateValues())
+
+	// JSON objects keep their order too
+	rows, err = parseJSONFixture([]byte(`[
+		{"table": "some_table", "pk": {"id": 1}, "ordered_fields": {"zeta": 2, "alpha": 1.5}}
+	]`))
+	assert.Nil(t, err)
+	rows[0].Init()
+	assert.Equal(t, []string{`"id"`, `"zeta"`, `"alpha"`}, rows[0].GetInsertColumns("postgres"))
+	assert.Equal(t, []interface{}{int64(1), int64(2), 1.5}, rows[0].GetInsertValues())
+
+	// A column can only be set once
+	row = &Row{
+		Table:         "some_table",
+		PK:            map[string]interface{}{"id": 1},
+		Fields:        map[string]interface{}{"alpha": "a"},
+		OrderedFields: OrderedFields{{Name: "alpha", Value: "a"}},
+	}
+	assert.EqualError(t, row.Validate(), "column alpha of table some_table is set in both fields and ordered_fields")
+	row.Fields = nil
+	row.OrderedFields = append(row.OrderedFields, Field{Name: "id", Value: 2})
+	assert.EqualError(t, row.Validate(), "column id of table some_table is set in both pk and fields")
+	row.OrderedFields = OrderedFields{{Name: "alpha", Value: "a"}, {Name: "alpha", Value: "b"}}
+	assert.EqualError(t, row.Validate(), "column alpha of table some_table is set twice in ordered_fields")
+}
+
+func TestRowGuardedByWhen(t *testing.T) {
+	row := &Row{
+		Table: "some_table",
+		PK:    map[string]interface{}{"id": 1},
+		When:  map[string]interface{}{"env": "dev", "tenant": 1},
+	}
+
+	// Every variable has to hold the expected value
+	assert.False(t, row.guarded(map[string]interface{}{"env": "dev", "tenant": int64(1)}))
+	assert.True(t, row.guarded(map[string]interface{}{"env": "prod", "tenant": 1}))
+	assert.True(t, row.guarded(map[string]interface{}{"env": "dev", "tenant": 2}))
+
+	// Unguarded rows are always loaded
+	assert.False(t, (&Row{Table: "some_table"}).guarded(nil))
+
+	// The variables have to be known
+	assert.EqualError(t, row.validateVars(map[string]interface{}{"env": "dev"}), "unknown variable tenant used by when")
+}
+
+func TestRowWithEscapedMarkers(t *testing.T) {
+	rows, err := parseFixture([]byte(`
+- table: 'some_table'
+  pk:
+    id: '\VAR(id)'
+  fields:
+    created_at: 'ON_INSERT_NOW()'
+    escaped_now: '\ON_INSERT_NOW()'
+    escaped_null: '\NULL()'
+    escaped_twice: '\\NULL()'
+    escaped_var: '\VAR(tenant'
+    path: '\\server\share'
+`))
+	assert.Nil(t, err)
+	row := &rows[0]
+	assert.Nil(t, row.Validate())
+	assert.Nil(t, row.validateVars(nil))
+	row.Init()
+
+	// Markers are resolved while their escaped forms are written as is,
+	// less their leading backslash
+	values := row.GetInsertValues()
+	assert.Equal(t, "VAR(id)", values[0])
+	assert.IsType(t, time.Time{}, values[1])
+	assert.Equal(
+		t,
+		[]interface{}{"ON_INSERT_NOW()", "NULL()", `\NULL()`, "VAR(tenant", `\\server\share`},
+		values[2:],
+	)
+	assert.Equal(t, []interface{}{"VAR(id)"}, row.GetPKValues())
+}
+
+func TestRowWithUpdateColumns(t *testing.T) {
+	row := &Row{
+		Table: "users",
+		PK:    map[string]interface{}{"id": interface{}(1)},
+		Fields: map[string]interface{}{
+			"email":      interface{}("foo@example.com"),
+			"name":       interface{}("Foo"),
+			"updated_at": interface{}("ON_UPDATE_NOW()"),
+		},
+		UpdateColumns: []string{"name", "updated_at"},
+	}
+	assert.Nil(t, row.Validate())
+	row.Init()
+
+	// Every column is inserted but only the listed ones are updated
+	assert.Equal(t, []string{"\"id\"", "\"email\"", "\"name\""}, row.GetInsertColumns("postgres"))
+	assert.Equal(t, []string{"\"name\"", "\"updated_at\""}, row.GetUpdateColumns("postgres"))
+	assert.Equal(t, 2, row.GetUpdateColumnsLength())
+	query, args := BuildUpdate(row, "postgres")
+	assert.Equal(t, `UPDATE "users" SET "name" = $1, "updated_at" = $2 WHERE "id" = $3`, query)
+	assert.Len(t, args, 3)
+
+	// The listed columns need a value
+	row.UpdateColumns = []string{"nickname"}
+	assert.EqualError(t, row.Validate(), "update_columns column nickname of table users is not set")
+}
+
+func TestRowResolveTable(t *testing.T) {
+	vars := map[string]interface{}{"tenant": "t1", "region": 2}
+
+	table, err := (&Row{Table: "users_{tenant}"}).resolveTable(vars)
+	assert.Nil(t, err)
+	assert.Equal(t, "users_t1", table)
+
+	// Several variables, schemas and spaces within the braces are fine
+	table, err = (&Row{Table: "tenant_{tenant}.users_{ region }"}).resolveTable(vars)
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant_t1.users_2", table)
+
+	// Names without variables are left alone
+	table, err = (&Row{Table: "users"}).resolveTable(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "users", table)
+
+	_, err = (&Row{Table: "users_{team}"}).resolveTable(vars)
+	assert.EqualError(t, err, "unknown variable team used by table name")
+	_, err = (&Row{Table: "users_{tenant"}).resolveTable(vars)
+	assert.EqualError(t, err, "unterminated variable in table name")
+}
+
+func TestRowWithColumnMap(t *testing.T) {
+	row := &Row{
+		Table: "users",
+		PK:    map[string]interface{}{"userId": interface{}(1)},
+		Fields: map[string]interface{}{
+			"email":     interface{}("foo@example.com"),
+			"firstName": interface{}("Foo"),
+			"createdAt": interface{}("ON_INSERT_NOW()"),
+		},
+		MatchOn:       []string{"email"},
+		UpdateColumns: []string{"firstName"},
+		Returning:     []string{"createdAt"},
+		columnMap: map[string]string{
+			"userId":    "user_id",
+			"firstName": "first_name",
+			"createdAt": "created_at",
+		},
+	}
+	row.Init()
+
+	// Mapped keys are written as their columns, unmapped ones as they are
+	assert.Equal(t, []string{`"user_id"`, `"created_at"`, `"email"`, `"first_name"`}, row.GetInsertColumns("postgres"))
+	assert.Equal(t, []string{`"first_name"`}, row.GetUpdateColumns("postgres"))
+	assert.Equal(t, []string{`"email"`}, row.GetMatchColumns("postgres"))
+	query, _ := BuildInsert(row, "postgres")
+	assert.Equal(t, `INSERT INTO "users"("user_id", "created_at", "email", "first_name") VALUES($1, $2, $3, $4) RETURNING "created_at"`, query)
+}
+
+func TestRowWithBytes(t *testing.T) {
+	row := &Row{
+		Table: "files",
+		PK:    map[string]interface{}{"id": interface{}(1)},
+		Fields: map[string]interface{}{
+			"contents": interface{}("BYTES(aGVsbG8=)"),
+			"empty":    interface{}("BYTES()"),
+			"literal":  interface{}(`\BYTES(aGVsbG8=)`),
+		},
+	}
+	assert.Nil(t, row.Validate())
+	row.Init()
+
+	// The contents are bound decoded, escaped markers as strings
+	assert.Equal(t, []interface{}{1, []byte("hello"), []byte{}, "BYTES(aGVsbG8=)"}, row.GetInsertValues())
+	assert.Equal(t, []interface{}{1, []byte("hello"), []byte{}, "BYTES(aGVsbG8=)"}, row.GetUpdateValues())
+}
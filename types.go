@@ -0,0 +1,270 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Types a column can be coerced to with the types of a row
+const (
+	int64Type   = "int64"
+	float64Type = "float64"
+	boolType    = "bool"
+	stringType  = "string"
+	timeType    = "time"
+)
+
+// timeLayouts are the layouts a string is parsed with when coerced to a time
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// knownType reports whether a column can be coerced to typ
+func knownType(typ string) bool {
+	switch typ {
+	case int64Type, float64Type, boolType, stringType, timeType:
+		return true
+	}
+	return false
+}
+
+// isMarker reports whether value is one of the reserved values, which are
+// never coerced
+func isMarker(value interface{}) bool {
+	sv, ok := value.(string)
+	if !ok {
+		return false
+	}
+	switch sv {
+	case onInsertNow, onUpdateNow, onInsertUUID, setNull:
+		return true
+	}
+	if _, ok := parseVar(sv); ok {
+		return true
+	}
+	if _, ok := parseBytes(sv); ok {
+		return true
+	}
+	_, ok = parseExpr(sv)
+	return ok
+}
+
+// coerceValue converts value to the type named typ, nil stays nil
+func coerceValue(value interface{}, typ string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch typ {
+	case int64Type:
+		return coerceInt64(value)
+	case float64Type:
+		return coerceFloat64(value)
+	case boolType:
+		return coerceBool(value)
+	case stringType:
+		return coerceString(value), nil
+	case timeType:
+		return coerceTime(value)
+	}
+	return nil, fmt.Errorf("unknown type %s", typ)
+}
+
+// coerceInt64 converts an integral number or a string holding one
+func coerceInt64(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), nil
+		}
+	case float64:
+		if v == math.Trunc(v) {
+			return int64(v), nil
+		}
+	case string:
+		if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", value, int64Type)
+}
+
+// coerceFloat64 converts a number or a string holding one
+func coerceFloat64(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", value, float64Type)
+}
+
+// coerceBool converts a boolean, 0 or 1, or a string such as yes, no, on,
+// off, true or false
+func coerceBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case int, int64:
+		switch fmt.Sprint(v) {
+		case "0":
+			return false, nil
+		case "1":
+			return true, nil
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "yes", "y", "on", "true", "t", "1":
+			return true, nil
+		case "no", "n", "off", "false", "f", "0":
+			return false, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", value, boolType)
+}
+
+// coerceString converts any value to its string representation, times are
+// formatted as RFC3339
+func coerceString(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// parseTimestamp returns a string formatted as an RFC3339 timestamp as a
+// time, any other value is returned as is
+func parseTimestamp(value interface{}) interface{} {
+	sv, ok := value.(string)
+	if !ok {
+		return value
+	}
+	t, err := time.Parse(time.RFC3339Nano, sv)
+	if err != nil {
+		return value
+	}
+	return t
+}
+
+// coerceTime converts a time or a string holding a date or a timestamp
+func coerceTime(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", value, timeType)
+}
+
+// serializeComplex returns a list as a Postgres array literal on postgres and
+// as JSON on other drivers and a map as JSON, as drivers cannot bind them.
+// Other values, and values which cannot be serialized, are returned as is
+func serializeComplex(driver string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		if driver == postgresDriver {
+			return postgresArray(v)
+		}
+	case map[interface{}]interface{}, map[string]interface{}, yaml.MapSlice:
+	default:
+		return value
+	}
+	serialized, err := json.Marshal(jsonValue(value))
+	if err != nil {
+		return value
+	}
+	return string(serialized)
+}
+
+// postgresArray returns values as a Postgres array literal such as
+// {1,"two",NULL}, nested lists become nested arrays and maps JSON strings
+func postgresArray(values []interface{}) string {
+	elements := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case nil:
+			elements[i] = "NULL"
+		case []interface{}:
+			elements[i] = postgresArray(v)
+		case string:
+			elements[i] = quoteArrayElement(v)
+		case time.Time:
+			elements[i] = quoteArrayElement(v.Format(time.RFC3339Nano))
+		case map[interface{}]interface{}, map[string]interface{}, yaml.MapSlice:
+			serialized, _ := json.Marshal(jsonValue(v))
+			elements[i] = quoteArrayElement(string(serialized))
+		default:
+			elements[i] = fmt.Sprint(v)
+		}
+	}
+	return "{" + strings.Join(elements, ",") + "}"
+}
+
+// arrayElementEscaper escapes the characters special within a quoted element
+// of a Postgres array literal
+var arrayElementEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// quoteArrayElement quotes s as an element of a Postgres array literal
+func quoteArrayElement(s string) string {
+	return `"` + arrayElementEscaper.Replace(s) + `"`
+}
+
+// jsonValue returns value with the maps decoded from YAML, whose keys are not
+// strings, turned into maps encoding/json can marshal
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return m
+	case yaml.MapSlice:
+		m := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			m[fmt.Sprint(item.Key)] = jsonValue(item.Value)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = jsonValue(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = jsonValue(item)
+		}
+		return list
+	}
+	return value
+}
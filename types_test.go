@@ -0,0 +1,126 @@
+package fixtures
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoerceValue(t *testing.T) {
+	var (
+		value interface{}
+		err   error
+	)
+
+	// Booleans from YAML's loose spellings
+	for _, v := range []interface{}{true, "yes", "Y", "on", "true", "1", 1} {
+		value, err = coerceValue(v, "bool")
+		assert.Nil(t, err)
+		assert.Equal(t, true, value, "%#v", v)
+	}
+	for _, v := range []interface{}{false, "no", "off", "false", "0", 0} {
+		value, err = coerceValue(v, "bool")
+		assert.Nil(t, err)
+		assert.Equal(t, false, value, "%#v", v)
+	}
+	_, err = coerceValue(2, "bool")
+	assert.EqualError(t, err, "cannot convert 2 to bool")
+
+	// Numbers
+	value, err = coerceValue("42", "int64")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), value)
+	value, err = coerceValue(float64(42), "int64")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), value)
+	_, err = coerceValue(4.2, "int64")
+	assert.EqualError(t, err, "cannot convert 4.2 to int64")
+	value, err = coerceValue(42, "float64")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(42), value)
+	value, err = coerceValue("4.2", "float64")
+	assert.Nil(t, err)
+	assert.Equal(t, 4.2, value)
+
+	// Strings
+	value, err = coerceValue(true, "string")
+	assert.Nil(t, err)
+	assert.Equal(t, "true", value)
+	value, err = coerceValue(42, "string")
+	assert.Nil(t, err)
+	assert.Equal(t, "42", value)
+
+	// Times
+	value, err = coerceValue("2023-01-02T15:04:05Z", "time")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC), value)
+	value, err = coerceValue("2023-01-02", "time")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), value)
+	_, err = coerceValue("tomorrow", "time")
+	assert.EqualError(t, err, "cannot convert tomorrow to time")
+
+	// NULL stays NULL whatever the type
+	value, err = coerceValue(nil, "int64")
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+
+	_, err = coerceValue(1, "uuid")
+	assert.EqualError(t, err, "unknown type uuid")
+}
+
+func TestParseTimestamp(t *testing.T) {
+	// A valid RFC3339 timestamp becomes a time
+	assert.Equal(
+		t,
+		time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC),
+		parseTimestamp("2023-01-02T15:04:05Z"),
+	)
+	assert.Equal(
+		t,
+		time.Date(2023, 1, 2, 15, 4, 5, 0, time.FixedZone("", 2*60*60)).Unix(),
+		parseTimestamp("2023-01-02T15:04:05+02:00").(time.Time).Unix(),
+	)
+
+	// Invalid timestamps, plain strings and other values are left untouched
+	assert.Equal(t, "2023-13-02T15:04:05Z", parseTimestamp("2023-13-02T15:04:05Z"))
+	assert.Equal(t, "2023-01-02 15:04:05", parseTimestamp("2023-01-02 15:04:05"))
+	assert.Equal(t, "20230102150405", parseTimestamp("20230102150405"))
+	assert.Equal(t, "order 66", parseTimestamp("order 66"))
+	assert.Equal(t, 20230102, parseTimestamp(20230102))
+}
+
+func TestSerializeComplex(t *testing.T) {
+	// Lists become Postgres array literals
+	assert.Equal(t, "{1,2,3}", serializeComplex("postgres", []interface{}{1, 2, 3}))
+	assert.Equal(
+		t,
+		`{"foo","say \"hi\"","C:\\tmp",NULL,true}`,
+		serializeComplex("postgres", []interface{}{"foo", `say "hi"`, `C:\tmp`, nil, true}),
+	)
+	assert.Equal(t, "{{1,2},{3,4}}", serializeComplex("postgres", []interface{}{
+		[]interface{}{1, 2},
+		[]interface{}{3, 4},
+	}))
+
+	// Or JSON on other drivers
+	assert.Equal(t, `[1,"two"]`, serializeComplex("mysql", []interface{}{1, "two"}))
+
+	// Maps decoded from YAML or JSON become JSON
+	assert.Equal(
+		t,
+		`{"1":true,"tags":["a","b"]}`,
+		serializeComplex("postgres", map[interface{}]interface{}{
+			1:      true,
+			"tags": []interface{}{"a", "b"},
+		}),
+	)
+	assert.Equal(t, `{"a":{"b":1}}`, serializeComplex("sqlite", map[string]interface{}{
+		"a": map[interface{}]interface{}{"b": 1},
+	}))
+
+	// Other values are left untouched
+	assert.Equal(t, "foo", serializeComplex("postgres", "foo"))
+	assert.Equal(t, 1, serializeComplex("postgres", 1))
+}